@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// openActionsReportName lists the open tasks of all documents, written into
+// the root path by -actions.
+const openActionsReportName = "_open_actions.md"
+
+// Action item types.
+const (
+	actionTodo     = "todo"
+	actionDecision = "decision"
+)
+
+// Where an action item was found.
+const (
+	actionFromCheckbox = "checkbox"
+	actionFromModel    = "model"
+)
+
+var (
+	// openTaskPattern matches an unchecked markdown task line.
+	openTaskPattern  = regexp.MustCompile(`^\s*[-*+]\s+\[ \]\s+(.+?)\s*$`)
+	taskDuePattern   = regexp.MustCompile(`(?:📅|due:?)\s*(\d{4}-\d{2}-\d{2})`)
+	taskOwnerPattern = regexp.MustCompile(`(?:^|\s)@([\p{L}\p{N}_.-]+)`)
+)
+
+// ActionItem is a task or decision found in a document. Chunk is the 1-based
+// chunk it came from.
+type ActionItem struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Owner  string `json:"owner,omitempty"`
+	Due    string `json:"due,omitempty"`
+	Chunk  int    `json:"chunk"`
+	Source string `json:"source"`
+}
+
+func buildActionPrompt(chunk string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that extracts action items from meeting and work notes.\n\n")
+	b.WriteString("Task:\n- Find the tasks (things someone still has to do) and the decisions that were made in the excerpt below.\n- For each task give the owner and the due date if the text names them, otherwise leave them empty.\n- Write each item as one short sentence in the SAME LANGUAGE as the text.\n- Do NOT invent items; if there are none, return an empty list.\n\n")
+	b.WriteString("Respond with JSON only, in exactly this form:\n{\"items\": [{\"type\": \"todo\", \"text\": \"Angebot an Kunden schicken\", \"owner\": \"Anna\", \"due\": \"2024-03-15\"}, {\"type\": \"decision\", \"text\": \"Wir bleiben beim bisherigen Anbieter\", \"owner\": \"\", \"due\": \"\"}]}\n\nExcerpt:\n---\n")
+	b.WriteString(chunk)
+	b.WriteString("\n---\n")
+	return b.String()
+}
+
+// findOpenTasks returns the unchecked "- [ ]" task lines of content, with
+// owner (@name) and due date (📅 or due: YYYY-MM-DD) when present. The
+// markers are removed from the task text.
+func findOpenTasks(content string, chunks []string) []ActionItem {
+	var items []ActionItem
+	for _, line := range strings.Split(content, "\n") {
+		m := openTaskPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		item := ActionItem{Type: actionTodo, Source: actionFromCheckbox}
+		if due := taskDuePattern.FindStringSubmatch(m[1]); due != nil {
+			item.Due = due[1]
+		}
+		if owner := taskOwnerPattern.FindStringSubmatch(m[1]); owner != nil {
+			item.Owner = owner[1]
+		}
+		// The markers are rendered from Owner and Due; keep them out of Text.
+		text := taskOwnerPattern.ReplaceAllString(taskDuePattern.ReplaceAllString(m[1], " "), " ")
+		item.Text = strings.Join(strings.Fields(text), " ")
+		if item.Text == "" {
+			continue
+		}
+		for i, chunk := range chunks {
+			if strings.Contains(chunk, line) {
+				item.Chunk = i + 1
+				break
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// extractActions combines the task lines of the source with the tasks and
+// decisions the model finds per chunk. Model items repeating a task line are
+// dropped.
+func extractActions(path, content string, chunks []string, cfg Config) []ActionItem {
+	items := findOpenTasks(content, chunks)
+	known := make(map[string]bool)
+	for _, item := range items {
+		known[normalizeActionText(item.Text)] = true
+	}
+	perChunk := extractPerChunk(path, "action items", chunks, cfg, func(chunk string) ([]ActionItem, error) {
+		var result struct {
+			Items []ActionItem `json:"items"`
+		}
+		err := askJSON(buildActionPrompt(chunk), &result, nil)
+		return result.Items, err
+	})
+	for i, found := range perChunk {
+		for _, item := range found {
+			item.Type = strings.ToLower(strings.TrimSpace(item.Type))
+			item.Text = strings.Join(strings.Fields(item.Text), " ")
+			key := normalizeActionText(item.Text)
+			if key == "" || known[key] || (item.Type != actionTodo && item.Type != actionDecision) {
+				continue
+			}
+			known[key] = true
+			if t, ok := findDate(item.Due); ok {
+				item.Due = t.Format("2006-01-02")
+			}
+			item.Owner = strings.TrimSpace(item.Owner)
+			item.Chunk = i + 1
+			item.Source = actionFromModel
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func normalizeActionText(text string) string {
+	return strings.ToLower(strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " "))
+}
+
+// actionLabels are the words of the action items section in German (the
+// default) and English.
+type actionLabels struct {
+	heading, decisions, owner, due string
+}
+
+func actionLabelsFor(lang string) actionLabels {
+	if lang == "en" {
+		return actionLabels{"## Action Items", "Decisions", "owner", "due"}
+	}
+	return actionLabels{"## Aufgaben", "Entscheidungen", "zuständig", "fällig"}
+}
+
+// formatAction renders a task or decision as one list line.
+func formatAction(item ActionItem, labels actionLabels) string {
+	var details []string
+	if item.Owner != "" {
+		details = append(details, labels.owner+": "+item.Owner)
+	}
+	if item.Due != "" {
+		details = append(details, labels.due+": "+item.Due)
+	}
+	if len(details) == 0 {
+		return "- " + item.Text
+	}
+	return fmt.Sprintf("- %s (%s)", item.Text, strings.Join(details, ", "))
+}
+
+// buildActionSection renders the action items appended to a summary. The
+// entries are plain list items so task plugins don't count them twice.
+func buildActionSection(items []ActionItem, lang string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	labels := actionLabelsFor(lang)
+	var todos, decisions []string
+	for _, item := range items {
+		if item.Type == actionDecision {
+			decisions = append(decisions, formatAction(item, labels))
+		} else {
+			todos = append(todos, formatAction(item, labels))
+		}
+	}
+	var b strings.Builder
+	b.WriteString("\n\n" + labels.heading + "\n\n")
+	if len(todos) > 0 {
+		b.WriteString(strings.Join(todos, "\n") + "\n")
+	}
+	if len(decisions) > 0 {
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", labels.decisions, strings.Join(decisions, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeOpenActionsReport lists the open tasks stored in the sidecars of plans
+// in _open_actions.md in the root path: tasks with a due date first, by date,
+// then the rest by document. It reports whether an error occurred.
+func writeOpenActionsReport(plans []string, cfg Config) bool {
+	type openTask struct {
+		item ActionItem
+		path string
+	}
+	var tasks []openTask
+	for _, path := range plans {
+		meta, err := readSummaryMeta(path)
+		if err != nil {
+			continue
+		}
+		for _, item := range meta.Actions {
+			if item.Type == actionTodo {
+				tasks = append(tasks, openTask{item: item, path: path})
+			}
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if (a.item.Due == "") != (b.item.Due == "") {
+			return a.item.Due != ""
+		}
+		if a.item.Due != b.item.Due {
+			return a.item.Due < b.item.Due
+		}
+		return a.path < b.path
+	})
+
+	labels := actionLabelsFor("")
+	var b strings.Builder
+	b.WriteString("# Offene Aufgaben\n\n")
+	if len(tasks) == 0 {
+		b.WriteString("No open action items found yet.\n")
+	}
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "%s — %s\n", formatAction(task.item, labels), markdownLink(task.path, cfg.RootDir))
+	}
+	return writeReport(filepath.Join(cfg.RootDir, openActionsReportName), b.String(), cfg)
+}
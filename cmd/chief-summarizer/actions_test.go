@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindOpenTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ActionItem
+	}{
+		{
+			name:    "plain task",
+			content: "- [ ] Bericht schreiben",
+			want:    []ActionItem{{Type: actionTodo, Text: "Bericht schreiben", Chunk: 1, Source: actionFromCheckbox}},
+		},
+		{
+			name:    "owner and due markers are stripped from the text",
+			content: "- [ ] Bericht schreiben @Anna due: 2024-03-15",
+			want:    []ActionItem{{Type: actionTodo, Text: "Bericht schreiben", Owner: "Anna", Due: "2024-03-15", Chunk: 1, Source: actionFromCheckbox}},
+		},
+		{
+			name:    "calendar emoji due date",
+			content: "* [ ] @Tom Angebot schicken 📅 2024-04-01",
+			want:    []ActionItem{{Type: actionTodo, Text: "Angebot schicken", Owner: "Tom", Due: "2024-04-01", Chunk: 1, Source: actionFromCheckbox}},
+		},
+		{
+			name:    "checked tasks and mail addresses are ignored",
+			content: "- [x] Erledigt\n- [ ] Mail an a@b.de schreiben",
+			want:    []ActionItem{{Type: actionTodo, Text: "Mail an a@b.de schreiben", Chunk: 1, Source: actionFromCheckbox}},
+		},
+		{
+			name:    "task with markers only is dropped",
+			content: "- [ ] @Anna due: 2024-03-15",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findOpenTasks(tt.content, []string{tt.content})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findOpenTasks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckboxTaskRenderedOnce(t *testing.T) {
+	items := findOpenTasks("- [ ] Bericht schreiben @Anna due: 2024-03-15", nil)
+	if len(items) != 1 {
+		t.Fatalf("got %d items, want 1", len(items))
+	}
+	if got, want := normalizeActionText(items[0].Text), normalizeActionText("Bericht schreiben."); got != want {
+		t.Errorf("dedup key = %q, want %q", got, want)
+	}
+	section := buildActionSection(items, "de")
+	if want := "- Bericht schreiben (zuständig: Anna, fällig: 2024-03-15)"; !strings.Contains(section, want) {
+		t.Errorf("section = %q, want it to contain %q", section, want)
+	}
+	if strings.Count(section, "Anna") != 1 || strings.Count(section, "2024-03-15") != 1 {
+		t.Errorf("owner or due date repeated in %q", section)
+	}
+}
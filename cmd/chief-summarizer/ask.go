@@ -0,0 +1,301 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// rrfK dampens the reciprocal rank fusion of keyword and embedding ranks.
+const rrfK = 60
+
+// passage is a retrievable piece of the collection: a summary or a chunk of a
+// source document.
+type passage struct {
+	label string // the file it comes from, as cited
+	text  string
+	terms []string
+	score float64
+}
+
+// runAsk implements the ask subcommand: it answers a question from the
+// summaries and source chunks most relevant to it.
+func runAsk(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		errorf("Usage: chief-summarizer ask \"<question>\" [flags] <root-path>\n")
+		return 2
+	}
+	question := args[0]
+	flags := flag.NewFlagSet("ask", flag.ExitOnError)
+	top := flags.Int("top", 6, "Number of passages given to the model")
+	cfg := parseFlags(flags, args[1:], "chief-summarizer ask \"<question>\" [flags] <root-path>",
+		"host", "model", "host-cooldown", "request-timeout", "embed-model", "index-file", "chunk-size", "chunk-overlap")
+	if *top < 1 {
+		errorf("ERR  invalid -top %d (must be at least 1)\n", *top)
+		return 2
+	}
+
+	plans, hadError := discoverFiles(cfg)
+	sort.Strings(plans)
+	passages := collectPassages(plans, cfg)
+	if len(passages) == 0 {
+		errorf("ERR  nothing to search in %s\n", cfg.RootDir)
+		return 1
+	}
+
+	httpClient.Timeout = cfg.RequestTimeout
+	ollamaPool = newHostPool(cfg.Hosts, cfg.HostCooldown, cfg)
+	model, err := ollamaPool.checkAll()
+	if err != nil {
+		errorf("ERR  model selection failed: %v\n", err)
+		return 1
+	}
+	cfg.Model = model
+
+	ranked := rankBM25(question, passages)
+	if cfg.EmbedModel != "" {
+		// Rerank a wider shortlist by meaning.
+		if reranked, err := rankByEmbedding(question, passages, ranked, *top*4, cfg); err != nil {
+			errorf("WARN embeddings unavailable, using keyword search only: %v\n", err)
+		} else {
+			ranked = reranked
+		}
+	}
+	if len(ranked) > *top {
+		ranked = ranked[:*top]
+	}
+	if len(ranked) == 0 {
+		statusf(cfg, "No passages match the question.\n")
+		return 1
+	}
+	if cfg.Verbose {
+		for _, p := range ranked {
+			statusf(cfg, "INFO %s (score %.3f)\n", p.label, p.score)
+		}
+	}
+
+	resp, err := callOllama(buildAskPrompt(question, ranked))
+	if err != nil {
+		errorf("ERR  %v\n", err)
+		return 1
+	}
+	fmt.Println(strings.TrimSpace(stripThinkBlocks(resp)))
+	fmt.Println("\nSources:")
+	seen := make(map[string]bool)
+	for _, p := range ranked {
+		if !seen[p.label] {
+			seen[p.label] = true
+			fmt.Printf("- %s\n", p.label)
+		}
+	}
+	if hadError {
+		return 1
+	}
+	return 0
+}
+
+// collectPassages returns the complete summaries and the chunks of the source
+// documents in plans.
+func collectPassages(plans []string, cfg Config) []*passage {
+	var passages []*passage
+	for _, path := range plans {
+		summaryPath := summaryFilename(path)
+		if summaryComplete(summaryPath) {
+			if data, err := os.ReadFile(summaryPath); err == nil {
+				text := summaryBody(string(data))
+				passages = append(passages, &passage{label: displayPath(summaryPath, cfg.RootDir), text: text})
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		chunks := chunkText(strings.TrimSpace(string(data)), cfg.ChunkSize, cfg.ChunkOverlap)
+		for i, chunk := range chunks {
+			label := displayPath(path, cfg.RootDir)
+			if len(chunks) > 1 {
+				label = fmt.Sprintf("%s (chunk %d/%d)", label, i+1, len(chunks))
+			}
+			passages = append(passages, &passage{label: label, text: chunk})
+		}
+	}
+	for _, p := range passages {
+		p.terms = searchTerms(p.text)
+	}
+	return passages
+}
+
+// searchTerms splits text into lowercase words of at least two letters or
+// digits.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len([]rune(word)) >= 2 {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// rankBM25 scores passages against query with Okapi BM25 and returns those
+// sharing at least one term with it, best first.
+func rankBM25(query string, passages []*passage) []*passage {
+	docFreq := make(map[string]int)
+	totalLen := 0
+	for _, p := range passages {
+		totalLen += len(p.terms)
+		seen := make(map[string]bool)
+		for _, term := range p.terms {
+			if !seen[term] {
+				seen[term] = true
+				docFreq[term]++
+			}
+		}
+	}
+	avgLen := float64(totalLen) / float64(len(passages))
+	n := float64(len(passages))
+
+	queryTerms := make(map[string]bool)
+	for _, term := range searchTerms(query) {
+		queryTerms[term] = true
+	}
+	var ranked []*passage
+	for _, p := range passages {
+		freq := make(map[string]int)
+		for _, term := range p.terms {
+			if queryTerms[term] {
+				freq[term]++
+			}
+		}
+		p.score = 0
+		for term, f := range freq {
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			tf := float64(f)
+			p.score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(len(p.terms))/avgLen))
+		}
+		if p.score > 0 {
+			ranked = append(ranked, p)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	return ranked
+}
+
+// rankByEmbedding combines the keyword ranking with a ranking by embedding
+// similarity to the question (reciprocal rank fusion). The candidates are the
+// best limit keyword matches and, from the embedding index, the limit passages
+// closest in meaning. Candidates missing from the index are embedded now.
+func rankByEmbedding(question string, passages, ranked []*passage, limit int, cfg Config) ([]*passage, error) {
+	idx, err := loadEmbeddingIndex(indexPath(cfg), cfg.EmbedModel)
+	if err != nil {
+		errorf("WARN %s (unreadable, ignored: %v)\n", displayPath(indexPath(cfg), cfg.RootDir), err)
+	}
+	query, err := callOllamaEmbed(cfg.EmbedModel, []string{question})
+	if err != nil {
+		return nil, err
+	}
+
+	similarity := make(map[*passage]float64)
+	vectorOf := func(p *passage) []float32 { return idx.Vectors[hashBytes([]byte(p.text))] }
+	var indexed []*passage
+	for _, p := range passages {
+		if vector := vectorOf(p); vector != nil {
+			similarity[p] = cosineSimilarity(query[0], vector)
+			indexed = append(indexed, p)
+		}
+	}
+	sort.SliceStable(indexed, func(i, j int) bool { return similarity[indexed[i]] > similarity[indexed[j]] })
+
+	keywordRank := make(map[*passage]int)
+	var candidates []*passage
+	for i, p := range ranked {
+		if i == limit {
+			break
+		}
+		keywordRank[p] = i + 1
+		candidates = append(candidates, p)
+	}
+	for i, p := range indexed {
+		if i == limit {
+			break
+		}
+		if keywordRank[p] == 0 {
+			candidates = append(candidates, p)
+		}
+	}
+
+	var missing []*passage
+	var texts []string
+	for _, p := range candidates {
+		if vectorOf(p) == nil {
+			missing = append(missing, p)
+			texts = append(texts, p.text)
+		}
+	}
+	if len(texts) > 0 {
+		vectors, err := callOllamaEmbed(cfg.EmbedModel, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range missing {
+			similarity[p] = cosineSimilarity(query[0], vectors[i])
+		}
+	}
+
+	byMeaning := append([]*passage(nil), candidates...)
+	sort.SliceStable(byMeaning, func(i, j int) bool { return similarity[byMeaning[i]] > similarity[byMeaning[j]] })
+	fused := make(map[*passage]float64)
+	for i, p := range byMeaning {
+		fused[p] = 1.0 / float64(rrfK+i+1)
+		if rank := keywordRank[p]; rank > 0 {
+			fused[p] += 1.0 / float64(rrfK+rank)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return fused[candidates[i]] > fused[candidates[j]] })
+	for _, p := range candidates {
+		p.score = fused[p]
+	}
+	return candidates, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		normA += x * x
+		normB += y * y
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func buildAskPrompt(question string, passages []*passage) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that answers questions about a personal notes collection (often a diary).\n\n")
+	b.WriteString("Task:\n- Answer the question below using ONLY the passages that follow. Each passage is labelled with the file it comes from.\n- Cite the file of every statement in square brackets, e.g. [2024/03-12.md].\n- If the passages do not contain the answer, say so plainly instead of guessing.\n- Answer in the SAME LANGUAGE as the question, in a few sentences or a short list.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
+	b.WriteString("Passages:\n---\n")
+	for _, p := range passages {
+		fmt.Fprintf(&b, "[%s]\n%s\n\n", p.label, p.text)
+	}
+	fmt.Fprintf(&b, "---\n\nQuestion: %s\n\nAnswer:\n", question)
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Anna war in Berlin.", []string{"anna", "war", "in", "berlin"}},
+		{"Über Müller's Äpfel", []string{"über", "müller", "äpfel"}},
+		{"am 12.03.2024 um 9 Uhr", []string{"am", "12", "03", "2024", "um", "uhr"}},
+		{"a - b", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := searchTerms(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchTerms(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankBM25(t *testing.T) {
+	passages := func(texts ...string) []*passage {
+		var ps []*passage
+		for i, text := range texts {
+			ps = append(ps, &passage{label: string(rune('a' + i)), text: text, terms: searchTerms(text)})
+		}
+		return ps
+	}
+	tests := []struct {
+		name     string
+		query    string
+		passages []*passage
+		want     []string // labels, best first
+	}{
+		{
+			name:     "passages without query terms are dropped",
+			query:    "Berlin",
+			passages: passages("Ich war in Berlin.", "Ich war in Hamburg.", "Berlin, Berlin!"),
+			want:     []string{"c", "a"},
+		},
+		{
+			name:     "rare terms weigh more than common ones",
+			query:    "Urlaub Tag",
+			passages: passages("Ein Tag im Büro.", "Der Tag war lang.", "Urlaub am Meer.", "Noch ein Tag."),
+			// "Noch ein Tag." is shorter than the two other passages about a day.
+			want: []string{"c", "d", "a", "b"},
+		},
+		{
+			name:     "shorter passages win at equal term frequency",
+			query:    "Anna",
+			passages: passages("Heute habe ich Anna nach langer Zeit wieder einmal in der Stadt getroffen.", "Anna angerufen."),
+			want:     []string{"b", "a"},
+		},
+		{
+			name:     "matching is case-insensitive",
+			query:    "ANNA berlin",
+			passages: passages("anna", "Berlin und Anna"),
+			want:     []string{"b", "a"},
+		},
+		{
+			name:     "equal scores keep the passage order",
+			query:    "Projekt",
+			passages: passages("Projekt A", "Projekt B", "Projekt C"),
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "no match",
+			query:    "Paris",
+			passages: passages("Berlin", "Hamburg"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range rankBM25(tt.query, tt.passages) {
+				got = append(got, p.label)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankBM25() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"same direction", []float32{1, 2, 3}, []float32{2, 4, 6}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opposite", []float32{1, 1}, []float32{-1, -1}, -1},
+		{"zero vector", []float32{0, 0}, []float32{1, 1}, 0},
+		{"different lengths", []float32{1, 2}, []float32{1, 2, 3}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+const checkpointVersion = 2
+
+// promptVersion identifies the prompt templates. Bump it whenever a prompt
+// changes so checkpoints produced with the old wording are discarded.
+const promptVersion = "1"
+
+// chunkerName identifies the algorithm in chunkText.
+const chunkerName = "runes"
+
+// chunkCheckpoint is the content of a <name>_chunks.json file. The binding
+// fields record what the summaries were produced from; a checkpoint is only
+// reused when all of them still match. For map-reduce Summaries holds one
+// summary per finished chunk and MergeStages the finished groups of each
+// intermediate merge stage; for refine Summaries holds the running summary
+// after Done chunks.
+type chunkCheckpoint struct {
+	Version       int        `json:"version"`
+	Strategy      string     `json:"strategy"`
+	SourceHash    string     `json:"source_hash"`
+	Chunker       string     `json:"chunker"`
+	ChunkSize     int        `json:"chunk_size"`
+	ChunkOverlap  int        `json:"chunk_overlap"`
+	Model         string     `json:"model"`
+	PromptVersion string     `json:"prompt_version"`
+	Done          int        `json:"done"`
+	Summaries     []string   `json:"summaries"`
+	MergeStages   [][]string `json:"merge_stages,omitempty"`
+}
+
+// readCheckpoint loads a checkpoint file. Files written by older versions
+// (a bare JSON array of chunk summaries) are returned with Version 0.
+func readCheckpoint(path string) (chunkCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return chunkCheckpoint{}, err
+	}
+	var legacy []string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		return chunkCheckpoint{Strategy: strategyMapReduce, Done: len(legacy), Summaries: legacy}, nil
+	}
+	var cp chunkCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return chunkCheckpoint{}, err
+	}
+	return cp, nil
+}
+
+func writeCheckpoint(path string, cp chunkCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// checkpointMismatches lists the binding fields in which saved differs from
+// want.
+func checkpointMismatches(saved, want chunkCheckpoint) []string {
+	var reasons []string
+	if saved.Version != want.Version {
+		reasons = append(reasons, "old checkpoint format")
+		return reasons
+	}
+	if saved.Strategy != want.Strategy {
+		reasons = append(reasons, "strategy "+saved.Strategy)
+	}
+	if saved.SourceHash != want.SourceHash {
+		reasons = append(reasons, "source changed")
+	}
+	if saved.Chunker != want.Chunker || saved.ChunkSize != want.ChunkSize || saved.ChunkOverlap != want.ChunkOverlap {
+		reasons = append(reasons, "chunk parameters changed")
+	}
+	if saved.Model != want.Model {
+		reasons = append(reasons, "model "+saved.Model)
+	}
+	if saved.PromptVersion != want.PromptVersion {
+		reasons = append(reasons, "prompts changed")
+	}
+	return reasons
+}
+
+// checkpointer persists the progress of one file. A nil checkpointer is valid
+// and saves nothing, for merges that are not backed by a source document.
+type checkpointer struct {
+	path string
+	cp   chunkCheckpoint
+	cfg  Config
+}
+
+// openCheckpointer prepares checkpointing for path. An existing checkpoint is
+// loaded only if it was produced with the same strategy, source content,
+// chunking, model and prompts; otherwise it is discarded.
+func openCheckpointer(path, strategy, sourceHash string, chunkCount int, cfg Config) *checkpointer {
+	c := &checkpointer{
+		path: chunksFilename(path),
+		cfg:  cfg,
+		cp: chunkCheckpoint{
+			Version:       checkpointVersion,
+			Strategy:      strategy,
+			SourceHash:    sourceHash,
+			Chunker:       chunkerName,
+			ChunkSize:     cfg.ChunkSize,
+			ChunkOverlap:  cfg.ChunkOverlap,
+			Model:         cfg.Model,
+			PromptVersion: promptVersion,
+		},
+	}
+	if cfg.Force {
+		return c
+	}
+	saved, err := readCheckpoint(c.path)
+	if err != nil {
+		return c
+	}
+	display := displayPath(path, cfg.RootDir)
+	if reasons := checkpointMismatches(saved, c.cp); len(reasons) > 0 {
+		statusf(cfg, "INFO %s (discarding checkpoint: %s)\n", display, strings.Join(reasons, ", "))
+		return c
+	}
+	if saved.Done > chunkCount || (saved.Strategy == strategyMapReduce && saved.Done != len(saved.Summaries)) {
+		statusf(cfg, "INFO %s (discarding inconsistent checkpoint)\n", display)
+		return c
+	}
+	c.cp = saved
+	if saved.Done > 0 {
+		statusf(cfg, "RESUME %s (loaded %d/%d chunks)\n", display, saved.Done, chunkCount)
+	}
+	return c
+}
+
+func (c *checkpointer) save() {
+	if c == nil {
+		return
+	}
+	if err := writeCheckpoint(c.path, c.cp); err != nil {
+		if c.cfg.Verbose {
+			statusf(c.cfg, "WARN failed to save checkpoint: %v\n", err)
+		}
+	}
+}
+
+// mergeStage returns the finished group results of intermediate merge stage
+// (1-based) from the checkpoint.
+func (c *checkpointer) mergeStage(stage int) []string {
+	if c == nil || stage > len(c.cp.MergeStages) {
+		return nil
+	}
+	return c.cp.MergeStages[stage-1]
+}
+
+// saveMergeStage records the finished group results of stage (1-based).
+func (c *checkpointer) saveMergeStage(stage int, results []string) {
+	if c == nil {
+		return
+	}
+	for len(c.cp.MergeStages) < stage {
+		c.cp.MergeStages = append(c.cp.MergeStages, nil)
+	}
+	c.cp.MergeStages[stage-1] = append([]string(nil), results...)
+	c.save()
+}
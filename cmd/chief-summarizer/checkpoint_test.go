@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointMismatches(t *testing.T) {
+	want := chunkCheckpoint{
+		Version:       checkpointVersion,
+		Strategy:      strategyMapReduce,
+		SourceHash:    "abc",
+		Chunker:       chunkerName,
+		ChunkSize:     4000,
+		ChunkOverlap:  400,
+		Model:         "qwen3:14b",
+		PromptVersion: "p1",
+	}
+	tests := []struct {
+		name   string
+		modify func(cp *chunkCheckpoint)
+		want   []string
+	}{
+		{name: "matching checkpoint", modify: func(cp *chunkCheckpoint) {}},
+		{name: "progress does not matter", modify: func(cp *chunkCheckpoint) { cp.Done = 3; cp.Summaries = []string{"a", "b", "c"} }},
+		{
+			name:   "old format hides everything else",
+			modify: func(cp *chunkCheckpoint) { cp.Version = 0; cp.Model = "llama3" },
+			want:   []string{"old checkpoint format"},
+		},
+		{name: "strategy", modify: func(cp *chunkCheckpoint) { cp.Strategy = strategyRefine }, want: []string{"strategy " + strategyRefine}},
+		{name: "source", modify: func(cp *chunkCheckpoint) { cp.SourceHash = "def" }, want: []string{"source changed"}},
+		{name: "chunk size", modify: func(cp *chunkCheckpoint) { cp.ChunkSize = 2000 }, want: []string{"chunk parameters changed"}},
+		{name: "chunk overlap", modify: func(cp *chunkCheckpoint) { cp.ChunkOverlap = 0 }, want: []string{"chunk parameters changed"}},
+		{name: "chunker", modify: func(cp *chunkCheckpoint) { cp.Chunker = "bytes" }, want: []string{"chunk parameters changed"}},
+		{name: "model", modify: func(cp *chunkCheckpoint) { cp.Model = "llama3" }, want: []string{"model llama3"}},
+		{name: "prompts", modify: func(cp *chunkCheckpoint) { cp.PromptVersion = "p0" }, want: []string{"prompts changed"}},
+		{
+			name:   "several fields in order",
+			modify: func(cp *chunkCheckpoint) { cp.SourceHash = "def"; cp.Model = "llama3"; cp.PromptVersion = "p0" },
+			want:   []string{"source changed", "model llama3", "prompts changed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := want
+			tt.modify(&saved)
+			if got := checkpointMismatches(saved, want); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkpointMismatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// artifactSource maps a generated artifact back to the markdown source it
+// belongs to. ok is false for paths that aren't artifacts.
+func artifactSource(path string) (string, bool) {
+	dir, base := filepath.Split(path)
+	if base == folderSummaryName {
+		return "", false
+	}
+	for _, suffix := range []string{"_summary.md", "_summary.json", "_chunks.json", "_tags.patch"} {
+		if len(base) > len(suffix) && strings.HasSuffix(base, suffix) {
+			return filepath.Join(dir, strings.TrimSuffix(base, suffix)+".md"), true
+		}
+	}
+	return "", false
+}
+
+// runClean implements `chief-summarizer clean [flags] <root-path>`.
+func runClean(args []string) int {
+	flags := flag.NewFlagSet("clean", flag.ExitOnError)
+	yes := flags.Bool("yes", false, "Actually delete files (default is a dry run)")
+	byModel := flags.String("by-model", "", "Also remove summaries whose footer names this model")
+	byVersion := flags.String("by-version", "", "Also remove summaries whose footer names this tool version (e.g. 1.0.0)")
+	cfg := parseFlags(flags, args, "chief-summarizer clean [flags] <root-path>", "state-file", "lock-name", "wait")
+	byVersionValue := strings.TrimPrefix(*byVersion, "v")
+
+	// Deleting while a run writes to the same root could remove fresh output.
+	if *yes {
+		lockFile, err := acquireLock(cfg)
+		if err != nil {
+			errorf("ERR  %v\n", err)
+			return 1
+		}
+		defer releaseLock(lockFile)
+	}
+
+	state, err := openState(cfg.StatePath)
+	if err != nil {
+		errorf("WARN state file %s unreadable: %v\n", cfg.StatePath, err)
+	}
+
+	hadError := false
+	removed := 0
+	remove := func(path, reason string) {
+		display := displayPath(path, cfg.RootDir)
+		if !*yes {
+			statusf(cfg, "DRY  %s (would delete: %s)\n", display, reason)
+			removed++
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			errorf("ERR  %s (delete failed: %v)\n", display, err)
+			hadError = true
+			return
+		}
+		statusf(cfg, "DEL  %s (%s)\n", display, reason)
+		removed++
+	}
+	// removeSummary also removes the sidecar and tag patch written with a
+	// summary; their source still exists, so they would never be orphaned.
+	removeSummary := func(path, reason string) {
+		remove(path, reason)
+		source := strings.TrimSuffix(path, "_summary.md") + ".md"
+		for _, sibling := range []string{summaryMetaFilename(source), tagsPatchFilename(source)} {
+			if _, err := os.Stat(sibling); err == nil {
+				remove(sibling, reason)
+			}
+		}
+	}
+
+	// Folder summaries are judged after the walk, once it is known which
+	// folders still hold source documents.
+	root := filepath.Clean(cfg.RootDir)
+	sourceDirs := make(map[string]bool)
+	var folderSummaries []string
+	filter := newPathFilter(cfg)
+	err = filepath.WalkDir(cfg.RootDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			errorf("ERR  %s (walk error: %v)\n", path, walkErr)
+			hadError = true
+			return nil
+		}
+		if filter.excluded(path, d.IsDir()) != "" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if isTempFile(path) {
+			remove(path, "leftover temporary file")
+			return nil
+		}
+		if filepath.Base(path) == folderSummaryName {
+			folderSummaries = append(folderSummaries, path)
+			return nil
+		}
+		if isMarkdown(path) && !isSummaryFile(path) && !isDigestFile(path) && !isReportFile(path) && !isOnThisDayNote(path, cfg) {
+			for dir := filepath.Dir(path); !sourceDirs[dir]; dir = filepath.Dir(dir) {
+				sourceDirs[dir] = true
+				if dir == root || filepath.Dir(dir) == dir {
+					break
+				}
+			}
+		}
+		source, ok := artifactSource(path)
+		if !ok {
+			return nil
+		}
+		_, statErr := os.Stat(source)
+		orphaned := errors.Is(statErr, fs.ErrNotExist)
+
+		if strings.HasSuffix(path, "_chunks.json") {
+			if orphaned {
+				remove(path, "checkpoint without source")
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, "_summary.json") {
+			if orphaned {
+				remove(path, "metadata without source")
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, "_tags.patch") {
+			if orphaned {
+				remove(path, "tag patch without source")
+			}
+			return nil
+		}
+
+		if !orphaned && *byModel == "" && byVersionValue == "" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errorf("ERR  %s (read failed: %v)\n", displayPath(path, cfg.RootDir), err)
+			hadError = true
+			return nil
+		}
+		// Only summaries carrying our footer are ours to delete; a
+		// hand-written meeting_summary.md is left alone.
+		footer, ok := parseSummaryFooter(string(data))
+		if !ok {
+			if orphaned {
+				statusf(cfg, "SKIP %s (no generator footer, kept)\n", displayPath(path, cfg.RootDir))
+			}
+			return nil
+		}
+		switch {
+		case orphaned:
+			remove(path, "summary without source")
+			if *yes {
+				state.Forget(source)
+			}
+		case *byModel != "" && footer.Model == *byModel:
+			removeSummary(path, "generated by model "+footer.Model)
+		case byVersionValue != "" && footer.Version == byVersionValue:
+			removeSummary(path, "generated by v"+footer.Version)
+		}
+		return nil
+	})
+	if err != nil {
+		errorf("ERR  walk error: %v\n", err)
+		hadError = true
+	}
+	for _, path := range folderSummaries {
+		if !sourceDirs[filepath.Dir(path)] {
+			remove(path, "folder summary without sources")
+		}
+	}
+
+	if *yes {
+		if err := state.Save(); err != nil {
+			errorf("WARN failed to save state: %v\n", err)
+		}
+		statusf(cfg, "Removed %d file(s).\n", removed)
+	} else {
+		statusf(cfg, "%d file(s) would be removed; re-run with -yes to delete.\n", removed)
+	}
+
+	if hadError {
+		return 1
+	}
+	return 0
+}
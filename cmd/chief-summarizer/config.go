@@ -0,0 +1,631 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileName = "chiefsummarizer.yaml"
+	// envPrefix starts every environment override, e.g.
+	// CHIEF_SUMMARIZER_PROCESSING_CHUNK_SIZE for processing.chunk_size.
+	envPrefix = "CHIEF_SUMMARIZER_"
+	// profilesKey is the top-level mapping of named profiles in the config file.
+	profilesKey = "profiles"
+)
+
+// configSetting describes one key of the config file for `config init` and
+// `config show`. Keys with a Flag take their default and help from it.
+type configSetting struct {
+	Key     string
+	Flag    string
+	Default string
+	Help    string
+}
+
+var configSettings = []configSetting{
+	{Key: "ollama.host", Flag: "host"},
+	{Key: "ollama.hosts", Default: "[]", Help: "Several Ollama hosts instead of host, e.g. [{url: http://gpu-1:11434, weight: 2, max_concurrency: 2}]"},
+	{Key: "ollama.host_cooldown", Flag: "host-cooldown"},
+	{Key: "ollama.embed_model", Flag: "embed-model"},
+	{Key: "ollama.preferred_models", Default: "[qwen3:14b, deepseek-r1:14b, llama3]", Help: "Models to use, in order of preference"},
+	{Key: "processing.root_path", Default: `""`, Help: "Directory to summarize when no path is given on the command line"},
+	{Key: "processing.chunk_size", Flag: "chunk-size"},
+	{Key: "processing.chunk_overlap", Flag: "chunk-overlap"},
+	{Key: "processing.request_timeout", Flag: "request-timeout"},
+	{Key: "processing.max_files", Flag: "max-files"},
+	{Key: "processing.order", Flag: "order"},
+	{Key: "processing.seed", Flag: "seed"},
+	{Key: "processing.strategy", Flag: "strategy"},
+	{Key: "processing.stuff_max_chars", Flag: "stuff-max-chars"},
+	{Key: "processing.lock_name", Flag: "lock-name"},
+	{Key: "processing.wait", Flag: "wait"},
+	{Key: "processing.rollup", Flag: "rollup"},
+	{Key: "output.force_overwrite", Flag: "force"},
+	{Key: "output.verbose", Flag: "verbose"},
+	{Key: "output.quiet", Flag: "quiet"},
+	{Key: "output.metrics_file", Flag: "metrics-file"},
+	{Key: "filters.exclude_patterns", Flag: "exclude", Default: "[]"},
+	{Key: "filters.include_patterns", Flag: "include", Default: "[]"},
+	{Key: "filters.use_gitignore", Flag: "gitignore"},
+	{Key: "digests.periods", Flag: "digest", Default: "[]"},
+	{Key: "digests.dir", Flag: "digest-dir"},
+	{Key: "index.enabled", Flag: "index"},
+	{Key: "index.path", Flag: "index-file"},
+	{Key: "onthisday.output", Default: onThisDayName, Help: "Note written by the onthisday subcommand, relative to the root path; runs never summarize it"},
+	{Key: "extraction.entities", Flag: "entities"},
+	{Key: "extraction.actions", Flag: "actions"},
+	{Key: "extraction.tags", Flag: "tags"},
+	{Key: "extraction.mood", Flag: "mood"},
+	{Key: "extraction.tag_vocabulary", Default: "[]", Help: "Tags -tags may propose, e.g. [arbeit, familie, reisen] (empty = any)"},
+	{Key: "updates.disable_autoupdate", Flag: "disable-autoupdate"},
+	{Key: "updates.channel", Default: channelStable, Help: "Release channel: " + strings.Join(updateChannels, ", ")},
+	{Key: "updates.check_interval", Default: "24h", Help: "How often to check for updates (0 = every run)"},
+	{Key: "updates.public_key", Default: `""`, Help: "Ed25519 public key (PEM) that must have signed a release's checksums.txt"},
+	{Key: "state.path", Flag: "state-file"},
+	{Key: "state.max_failures", Flag: "max-failures"},
+	{Key: "state.retry_backoff", Flag: "retry-backoff"},
+	{Key: "validation.disable", Flag: "disable-validation"},
+	{Key: "validation.retries", Flag: "validation-retries"},
+	{Key: "validation.min_length", Flag: "min-summary-length"},
+	{Key: "validation.max_length", Flag: "max-summary-length"},
+	{Key: "validation.fact_check", Flag: "fact-check"},
+}
+
+// loadedConfig is the config file after environment overrides, together with
+// the keys each source provided.
+type loadedConfig struct {
+	File   *ConfigFile
+	Path   string
+	Exists bool
+	// Profile is the profile layered over the base settings, if any.
+	Profile string
+	// FileKeys and EnvKeys record which section.key settings came from the
+	// file (including the profile) and from which environment variable;
+	// ProfileKeys those the profile set.
+	FileKeys    map[string]bool
+	ProfileKeys map[string]bool
+	EnvKeys     map[string]string
+	// Unknown lists keys in the file that Chief Summarizer does not use.
+	Unknown []string
+}
+
+// configPathFor returns the config file to use: -config, then
+// $CHIEF_SUMMARIZER_CONFIG, then $XDG_CONFIG_HOME/chiefsummarizer.yaml
+// (~/.config by default).
+func configPathFor(flagPath, homeDir string) string {
+	if flagPath != "" {
+		return expandHome(flagPath, homeDir)
+	}
+	if env := os.Getenv(envPrefix + "CONFIG"); env != "" {
+		return expandHome(env, homeDir)
+	}
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		base = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(base, configFileName)
+}
+
+// profileFor returns the profile to use: -profile, then
+// $CHIEF_SUMMARIZER_PROFILE.
+func profileFor(flagProfile string) string {
+	if flagProfile != "" {
+		return flagProfile
+	}
+	return os.Getenv(envPrefix + "PROFILE")
+}
+
+// has reports whether the config file, profile or environment set key.
+func (l *loadedConfig) has(key string) bool {
+	return l.FileKeys[key] || l.EnvKeys[key] != ""
+}
+
+// loadConfig reads the config file at path, if it exists, layers the named
+// profile over its base settings and applies CHIEF_SUMMARIZER_* environment
+// overrides on top of both.
+func loadConfig(path, profile string) (*loadedConfig, error) {
+	loaded := &loadedConfig{
+		File:        &ConfigFile{},
+		Path:        path,
+		Profile:     profile,
+		FileKeys:    make(map[string]bool),
+		ProfileKeys: make(map[string]bool),
+		EnvKeys:     make(map[string]string),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var root *yaml.Node
+	if err == nil {
+		loaded.Exists = true
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 {
+			root = doc.Content[0]
+			checkConfigKeys(root, reflect.TypeOf(ConfigFile{}), "", loaded)
+		}
+	}
+	if profile != "" {
+		overlay := mappingValue(mappingValue(root, profilesKey), profile)
+		if overlay == nil || overlay.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("profile %q not found in %s (available: %s)", profile, path, strings.Join(profileNames(root), ", "))
+		}
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			section := overlay.Content[i+1]
+			for j := 0; section.Kind == yaml.MappingNode && j+1 < len(section.Content); j += 2 {
+				key := overlay.Content[i].Value + "." + section.Content[j].Value
+				loaded.FileKeys[key] = true
+				loaded.ProfileKeys[key] = true
+			}
+		}
+		mergeNodes(root, overlay)
+	}
+	if root != nil {
+		if err := root.Decode(loaded.File); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnvOverrides(loaded); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// mappingValue returns the value of key in the mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// profileNames lists the profiles defined in the config file.
+func profileNames(root *yaml.Node) []string {
+	profiles := mappingValue(root, profilesKey)
+	if profiles == nil || profiles.Kind != yaml.MappingNode || len(profiles.Content) == 0 {
+		return []string{"none"}
+	}
+	var names []string
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		names = append(names, profiles.Content[i].Value)
+	}
+	return names
+}
+
+// mergeNodes overlays the mapping src onto dst: nested mappings are merged
+// key by key, any other value in src replaces the one in dst.
+func mergeNodes(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value)
+		case existing != nil:
+			*existing = *value
+		default:
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
+
+// checkConfigKeys walks node alongside the ConfigFile type t, recording the
+// settings present and any keys without a matching field.
+func checkConfigKeys(node *yaml.Node, t reflect.Type, prefix string, loaded *loadedConfig) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			key := prefix + keyNode.Value
+			field, ok := yamlField(t, keyNode.Value)
+			if !ok {
+				loaded.Unknown = append(loaded.Unknown, fmt.Sprintf("%s (line %d)", key, keyNode.Line))
+				continue
+			}
+			if strings.Count(key, ".") == 1 && !strings.HasPrefix(key, profilesKey+".") {
+				loaded.FileKeys[key] = true
+			}
+			checkConfigKeys(valueNode, field.Type, key+".", loaded)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkConfigKeys(node.Content[i+1], t.Elem(), prefix+node.Content[i].Value+".", loaded)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			checkConfigKeys(item, t.Elem(), fmt.Sprintf("%s[%d].", strings.TrimSuffix(prefix, "."), i), loaded)
+		}
+	}
+}
+
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("yaml"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// applyEnvOverrides sets every section.key of the config file that has a
+// CHIEF_SUMMARIZER_SECTION_KEY environment variable. Strings are taken as is;
+// other values, including lists like "[a, b]", are parsed as YAML.
+func applyEnvOverrides(loaded *loadedConfig) error {
+	sections := reflect.ValueOf(loaded.File).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		sectionName := strings.Split(sections.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		section := sections.Field(i)
+		if section.Kind() != reflect.Struct {
+			continue
+		}
+		for j := 0; j < section.NumField(); j++ {
+			keyName := strings.Split(section.Type().Field(j).Tag.Get("yaml"), ",")[0]
+			envName := envPrefix + strings.ToUpper(sectionName+"_"+keyName)
+			value, ok := os.LookupEnv(envName)
+			if !ok {
+				continue
+			}
+			field := section.Field(j)
+			if field.Kind() == reflect.String {
+				field.SetString(value)
+			} else if field.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(value), "[") && field.Type().Elem().Kind() == reflect.String {
+				field.Set(reflect.ValueOf(strings.Split(value, ",")))
+			} else if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+				return fmt.Errorf("%s: %w", envName, err)
+			}
+			loaded.EnvKeys[sectionName+"."+keyName] = envName
+		}
+	}
+	return nil
+}
+
+// validateConfigFile reports values in configFile that are not usable: bad
+// durations, regular expressions, enumerated settings and numbers out of
+// range. set reports whether a key was given; a given key may not be empty.
+func validateConfigFile(configFile *ConfigFile, set func(key string) bool) []string {
+	var problems []string
+	required := []struct {
+		key, value string
+	}{
+		{"ollama.host", configFile.Ollama.Host},
+		{"processing.lock_name", configFile.Processing.LockName},
+		{"index.path", configFile.Index.Path},
+		{"onthisday.output", configFile.OnThisDay.Output},
+	}
+	for _, r := range required {
+		if set(r.key) && r.value == "" {
+			problems = append(problems, fmt.Sprintf("%s: must not be empty", r.key))
+		}
+	}
+	numbers := []struct {
+		key      string
+		value    int
+		positive bool
+	}{
+		{"processing.chunk_size", configFile.Processing.ChunkSize, true},
+		{"processing.chunk_overlap", configFile.Processing.ChunkOverlap, false},
+		{"processing.max_files", configFile.Processing.MaxFiles, false},
+		{"processing.stuff_max_chars", configFile.Processing.StuffMaxChars, true},
+		{"state.max_failures", configFile.State.MaxFailures, false},
+		{"validation.min_length", configFile.Validation.MinLength, false},
+		{"validation.max_length", configFile.Validation.MaxLength, false},
+	}
+	for _, n := range numbers {
+		switch {
+		case !set(n.key):
+		case n.positive && n.value <= 0:
+			problems = append(problems, fmt.Sprintf("%s: must be positive, got %d", n.key, n.value))
+		case n.value < 0:
+			problems = append(problems, fmt.Sprintf("%s: must not be negative, got %d", n.key, n.value))
+		}
+	}
+	durations := []struct {
+		key, value string
+	}{
+		{"ollama.host_cooldown", configFile.Ollama.HostCooldown},
+		{"processing.request_timeout", configFile.Processing.RequestTimeout},
+		{"processing.wait", configFile.Processing.Wait},
+		{"state.retry_backoff", configFile.State.RetryBackoff},
+		{"updates.check_interval", configFile.Updates.CheckInterval},
+	}
+	for _, d := range durations {
+		if !set(d.key) && d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid duration %q (use e.g. 90s, 10m, 2h)", d.key, d.value))
+		}
+	}
+	for _, pattern := range configFile.Filters.ExcludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("filters.exclude_patterns: invalid regular expression %q: %v", pattern, err))
+		}
+	}
+	for _, pattern := range configFile.Filters.IncludePatterns {
+		if _, err := compileInclude(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("filters.include_patterns: invalid pattern %q: %v", pattern, err))
+		}
+	}
+	enums := []struct {
+		key, value string
+		allowed    []string
+	}{
+		{"processing.order", configFile.Processing.Order, processingOrders},
+		{"processing.strategy", configFile.Processing.Strategy, summarizationStrategies},
+		{"validation.fact_check", configFile.Validation.FactCheck, factCheckModes},
+		{"updates.channel", configFile.Updates.Channel, updateChannels},
+	}
+	for _, e := range enums {
+		if (set(e.key) || e.value != "") && !containsString(e.allowed, e.value) {
+			problems = append(problems, fmt.Sprintf("%s: invalid value %q (expected one of: %s)", e.key, e.value, strings.Join(e.allowed, ", ")))
+		}
+	}
+	if _, err := parseDigestPeriods(strings.Join(configFile.Digests.Periods, ",")); err != nil {
+		problems = append(problems, "digests.periods: "+err.Error())
+	}
+	for i, host := range configFile.Ollama.Hosts {
+		if host.URL == "" {
+			problems = append(problems, fmt.Sprintf("ollama.hosts[%d]: url is required", i))
+		}
+	}
+	if configFile.Updates.PublicKey != "" {
+		if _, err := parsePublicKey(configFile.Updates.PublicKey); err != nil {
+			problems = append(problems, fmt.Sprintf("updates.public_key: %v", err))
+		}
+	}
+	return problems
+}
+
+// runConfig implements `chief-summarizer config init|validate|show`.
+func runConfig(args []string) int {
+	usage := "chief-summarizer config init|validate|show [flags]"
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n", usage)
+		return 2
+	}
+	switch args[0] {
+	case "init":
+		return runConfigInit(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "show":
+		return runConfigShow(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "ERR  unknown config command %q\nUsage: %s\n", args[0], usage)
+	return 2
+}
+
+func runConfigInit(args []string) int {
+	flags := flag.NewFlagSet("config init", flag.ExitOnError)
+	configPath := flags.String("config", "", "Where to write the config file (default $XDG_CONFIG_HOME/chiefsummarizer.yaml)")
+	force := flags.Bool("force", false, "Overwrite an existing config file")
+	flags.Parse(args)
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		errorf("ERR  determine home directory: %v\n", err)
+		return 1
+	}
+	path := configPathFor(*configPath, homeDir)
+	if _, err := os.Stat(path); err == nil && !*force {
+		errorf("ERR  %s already exists (use -force to overwrite)\n", path)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		errorf("ERR  %v\n", err)
+		return 1
+	}
+	if err := writeFileAtomic(path, []byte(renderDefaultConfig()), 0o644); err != nil {
+		errorf("ERR  write %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return 0
+}
+
+// renderDefaultConfig returns a config file listing every setting with its
+// default value, commented out.
+func renderDefaultConfig() string {
+	defaults := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	registerFlags(defaults)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Chief Summarizer configuration (written by chief-summarizer v%s).\n", version)
+	b.WriteString("# Uncomment a setting to change it. Command line flags and CHIEF_SUMMARIZER_*\n")
+	b.WriteString("# environment variables (e.g. CHIEF_SUMMARIZER_PROCESSING_CHUNK_SIZE) override it.\n")
+	section := ""
+	for _, setting := range configSettings {
+		sectionName, key, _ := strings.Cut(setting.Key, ".")
+		if sectionName != section {
+			fmt.Fprintf(&b, "\n%s:\n", sectionName)
+			section = sectionName
+		}
+		value, help := setting.Default, setting.Help
+		if f := defaults.Lookup(setting.Flag); f != nil {
+			help = f.Usage + " (-" + f.Name + ")"
+			if value == "" {
+				value = f.DefValue
+			}
+			if value == "" {
+				value = `""`
+			}
+		}
+		fmt.Fprintf(&b, "  # %s\n  # %s: %s\n", help, key, value)
+	}
+	b.WriteString("\n# Named profiles, selected with -profile NAME, override the settings above:\n")
+	b.WriteString("# profiles:\n#   work:\n#     ollama:\n#       host: http://gpu-server:11434\n#     output:\n#       force_overwrite: false\n")
+	return b.String()
+}
+
+func runConfigValidate(args []string) int {
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configPath := flags.String("config", "", "Config file to check (default $XDG_CONFIG_HOME/chiefsummarizer.yaml)")
+	profile := flags.String("profile", "", "Also check the settings with this profile applied (default $CHIEF_SUMMARIZER_PROFILE)")
+	flags.Parse(args)
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		errorf("ERR  determine home directory: %v\n", err)
+		return 1
+	}
+	path := configPathFor(*configPath, homeDir)
+	loaded, err := loadConfig(path, profileFor(*profile))
+	if err != nil {
+		errorf("ERR  %s: %v\n", path, err)
+		return 1
+	}
+	if !loaded.Exists {
+		errorf("WARN %s does not exist; only environment overrides were checked\n", path)
+	}
+	problems := validateConfigFile(loaded.File, loaded.has)
+	names := make([]string, 0, len(loaded.File.Profiles))
+	for name := range loaded.File.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Validate each profile as layered over the base settings, reporting only
+	// what the base settings do not already get wrong.
+	for _, name := range names {
+		profileLoaded, err := loadConfig(path, name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s.%s: %v", profilesKey, name, err))
+			continue
+		}
+		for _, problem := range validateConfigFile(profileLoaded.File, profileLoaded.has) {
+			if !containsString(problems, problem) {
+				problems = append(problems, profilesKey+"."+name+"."+problem)
+			}
+		}
+	}
+	for _, key := range loaded.Unknown {
+		problems = append(problems, "unknown key "+key)
+	}
+	for _, problem := range problems {
+		errorf("ERR  %s: %s\n", path, problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Printf("OK   %s\n", path)
+	return 0
+}
+
+// runConfigShow prints the effective value of every setting and where it
+// came from: a flag, an environment variable, the config file or the default.
+func runConfigShow(args []string) int {
+	flags := flag.NewFlagSet("config show", flag.ExitOnError)
+	o := registerFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chief-summarizer config show [flags] [rootPath]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		errorf("ERR  determine home directory: %v\n", err)
+		return 1
+	}
+	loaded, err := loadConfig(configPathFor(o.configPath, homeDir), profileFor(o.profile))
+	if err != nil {
+		errorf("ERR  failed to load config file: %v\n", err)
+		return 1
+	}
+	status := "not found, using defaults"
+	if loaded.Exists {
+		status = "loaded"
+	}
+	fmt.Printf("# Config file: %s (%s)\n", loaded.Path, status)
+	if loaded.Profile != "" {
+		fmt.Printf("# Profile: %s\n", loaded.Profile)
+	}
+	problems := validateConfigFile(loaded.File, loaded.has)
+	if len(problems) == 0 {
+		if err := applyConfigFile(o, flags, loaded, homeDir); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, problem := range problems {
+		errorf("ERR  %s: %s\n", loaded.Path, problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+
+	setFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	for _, setting := range configSettings {
+		var value string
+		switch setting.Key {
+		case "ollama.hosts":
+			hosts := make([]string, 0, len(o.cfg.Hosts))
+			for _, h := range o.cfg.Hosts {
+				weight, concurrency := max(h.Weight, 1), max(h.MaxConcurrency, 1)
+				hosts = append(hosts, fmt.Sprintf("%s (weight %d, max concurrency %d)", h.URL, weight, concurrency))
+			}
+			value = strings.Join(hosts, ", ")
+		case "ollama.preferred_models":
+			value = strings.Join(preferredModels, ", ")
+		case "extraction.tag_vocabulary":
+			value = strings.Join(o.cfg.TagVocabulary, ", ")
+		case "processing.root_path":
+			value = loaded.File.Processing.RootPath
+			if flags.NArg() > 0 {
+				value = flags.Arg(0)
+			}
+		case "updates.channel":
+			value = o.cfg.Updates.Channel
+		case "updates.check_interval":
+			value = o.cfg.Updates.CheckInterval.String()
+		case "onthisday.output":
+			value = o.cfg.OnThisDayFile
+		case "updates.public_key":
+			if o.cfg.Updates.PublicKey != "" {
+				value = "(set)"
+			}
+		default:
+			value = flags.Lookup(setting.Flag).Value.String()
+		}
+
+		source := "default"
+		switch {
+		case setting.Flag != "" && setFlags[setting.Flag]:
+			source = "flag -" + setting.Flag
+		case setting.Key == "processing.root_path" && flags.NArg() > 0:
+			source = "argument"
+		case loaded.EnvKeys[setting.Key] != "":
+			source = "env " + loaded.EnvKeys[setting.Key]
+		case loaded.ProfileKeys[setting.Key]:
+			source = "profile " + loaded.Profile
+		case loaded.FileKeys[setting.Key]:
+			source = "config file"
+		}
+		fmt.Printf("%-28s = %-30s # %s\n", setting.Key, value, source)
+	}
+	return 0
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported values for -digest.
+const (
+	periodWeekly  = "weekly"
+	periodMonthly = "monthly"
+	periodYearly  = "yearly"
+)
+
+var digestPeriods = []string{periodWeekly, periodMonthly, periodYearly}
+
+const digestSuffix = "_digest.md"
+
+var (
+	isoDateParts       = regexp.MustCompile(`(?:^|\D)(\d{4})-(\d{2})-(\d{2})(?:\D|$)`)
+	compactDateParts   = regexp.MustCompile(`(?:^|\D)(\d{4})(\d{2})(\d{2})(?:\D|$)`)
+	germanDateParts    = regexp.MustCompile(`(?:^|\D)(\d{1,2})\.\s?(\d{1,2})\.\s?(\d{4})(?:\D|$)`)
+	frontmatterDateKey = []string{"date", "created"}
+)
+
+func isDigestFile(path string) bool {
+	return strings.HasSuffix(filepath.Base(path), digestSuffix)
+}
+
+// parseDigestPeriods splits a comma-separated -digest value and checks each
+// period.
+func parseDigestPeriods(value string) ([]string, error) {
+	var periods []string
+	for _, period := range strings.Split(value, ",") {
+		period = strings.TrimSpace(period)
+		if period == "" {
+			continue
+		}
+		if !containsString(digestPeriods, period) {
+			return nil, fmt.Errorf("invalid period %q (expected: %s)", period, strings.Join(digestPeriods, ", "))
+		}
+		if !containsString(periods, period) {
+			periods = append(periods, period)
+		}
+	}
+	return periods, nil
+}
+
+// periodKey names the period of the given kind that contains t, e.g.
+// 2024-W10, 2024-03 or 2024.
+func periodKey(period string, t time.Time) string {
+	switch period {
+	case periodWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case periodMonthly:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006")
+	}
+}
+
+// documentDates returns the dates a diary document covers: the date in its
+// file name, else the date or created key of its frontmatter, else every
+// date found in its headings.
+func documentDates(path string, content string) []time.Time {
+	if t, ok := findDate(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))); ok {
+		return []time.Time{t}
+	}
+	if t, ok := frontmatterDate(content); ok {
+		return []time.Time{t}
+	}
+	var dates []time.Time
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if t, ok := findDate(line); ok {
+			dates = append(dates, t)
+		}
+	}
+	return dates
+}
+
+// datedSection is the text under a heading that contains a date.
+type datedSection struct {
+	date time.Time
+	text string
+}
+
+// datedSections splits a diary document into the sections under headings with
+// a date. Text before the first such heading is left out.
+func datedSections(content string) []datedSection {
+	var sections []datedSection
+	var text strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			if t, ok := findDate(line); ok {
+				if len(sections) > 0 {
+					sections[len(sections)-1].text = strings.TrimSpace(text.String())
+				}
+				sections = append(sections, datedSection{date: t})
+				text.Reset()
+				continue
+			}
+		}
+		text.WriteString(line + "\n")
+	}
+	if len(sections) > 0 {
+		sections[len(sections)-1].text = strings.TrimSpace(text.String())
+	}
+	return sections
+}
+
+// findDate finds a YYYY-MM-DD, YYYYMMDD or DD.MM.YYYY date in s.
+func findDate(s string) (time.Time, bool) {
+	if m := isoDateParts.FindStringSubmatch(s); m != nil {
+		return makeDate(m[1], m[2], m[3])
+	}
+	if m := germanDateParts.FindStringSubmatch(s); m != nil {
+		return makeDate(m[3], m[2], m[1])
+	}
+	if m := compactDateParts.FindStringSubmatch(s); m != nil {
+		return makeDate(m[1], m[2], m[3])
+	}
+	return time.Time{}, false
+}
+
+func makeDate(year, month, day string) (time.Time, bool) {
+	y, _ := strconv.Atoi(year)
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// splitFrontmatter returns the YAML frontmatter of a markdown document and
+// the text after it.
+func splitFrontmatter(content string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		rest, ok = strings.CutPrefix(content, "---\r\n")
+	}
+	if !ok {
+		return "", content, false
+	}
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return "", content, false
+	}
+	body := rest[end+len("\n---"):]
+	if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+		body = body[nl+1:]
+	} else {
+		body = ""
+	}
+	return rest[:end+1], body, true
+}
+
+func frontmatterDate(content string) (time.Time, bool) {
+	front, _, ok := splitFrontmatter(content)
+	if !ok {
+		return time.Time{}, false
+	}
+	var fields map[string]any
+	if yaml.Unmarshal([]byte(front), &fields) != nil {
+		return time.Time{}, false
+	}
+	for _, key := range frontmatterDateKey {
+		switch v := fields[key].(type) {
+		case time.Time:
+			return time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC), true
+		case string:
+			if t, ok := findDate(v); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// summaryBody returns a summary without its frontmatter and footer.
+func summaryBody(summary string) string {
+	if _, body, ok := splitFrontmatter(summary); ok {
+		summary = body
+	}
+	if loc := footerPattern.FindStringIndex(summary); loc != nil {
+		summary = strings.TrimSuffix(strings.TrimSpace(summary[:loc[0]]), "---")
+	}
+	return strings.TrimSpace(summary)
+}
+
+type digestInput struct {
+	date time.Time
+	text string
+}
+
+// writeDigests writes one digest per period of each kind in cfg.Digest into
+// cfg.DigestDir, built from the summaries of the documents dated within the
+// period. Periods whose inputs did not change are skipped. It reports
+// whether an error occurred.
+func writeDigests(plans []string, cfg Config) bool {
+	periods, _ := parseDigestPeriods(cfg.Digest)
+	grouped := make(map[string]map[string][]digestInput)
+	for _, period := range periods {
+		grouped[period] = make(map[string][]digestInput)
+	}
+	for _, path := range plans {
+		summaryPath := summaryFilename(path)
+		if !summaryComplete(summaryPath) {
+			continue
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		dates := documentDates(path, string(source))
+		if len(dates) == 0 {
+			if cfg.Verbose {
+				statusf(cfg, "SKIP %s (no date for digests)\n", displayPath(path, cfg.RootDir))
+			}
+			continue
+		}
+		summary, err := os.ReadFile(summaryPath)
+		if err != nil {
+			continue
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		text := fmt.Sprintf("%s (%s):\n%s", displayPath(path, cfg.RootDir), dates[0].Format("2006-01-02"), summaryBody(string(summary)))
+		for _, period := range periods {
+			added := make(map[string]bool)
+			for _, date := range dates {
+				key := periodKey(period, date)
+				if !added[key] {
+					added[key] = true
+					grouped[period][key] = append(grouped[period][key], digestInput{date: dates[0], text: text})
+				}
+			}
+		}
+	}
+
+	hadError := false
+	dir := cfg.DigestDir
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cfg.RootDir, dir)
+	}
+	for _, period := range periods {
+		keys := make([]string, 0, len(grouped[period]))
+		for key := range grouped[period] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			entries := grouped[period][key]
+			sort.Slice(entries, func(i, j int) bool {
+				if !entries[i].date.Equal(entries[j].date) {
+					return entries[i].date.Before(entries[j].date)
+				}
+				return entries[i].text < entries[j].text
+			})
+			inputs := make([]string, len(entries))
+			for i, entry := range entries {
+				inputs[i] = entry.text
+			}
+			target := filepath.Join(dir, key+digestSuffix)
+			display := displayPath(target, cfg.RootDir)
+			hash := hashBytes([]byte(strings.Join(inputs, "\n\n")))
+			if !cfg.Force && upToDate(target, hash) {
+				if cfg.Verbose {
+					statusf(cfg, "SKIP %s (digest up to date)\n", display)
+				}
+				continue
+			}
+			if cfg.DryRun {
+				statusf(cfg, "DRY  %s (would create from %d summaries)\n", display, len(inputs))
+				continue
+			}
+			statusf(cfg, "DGST %s (%d summaries)\n", display, len(inputs))
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				errorf("ERR  %s (%v)\n", display, err)
+				return true
+			}
+			if err := writeDigest(target, period, key, inputs, hash, cfg); err != nil {
+				errorf("ERR  %s (digest: %v)\n", display, err)
+				hadError = true
+				continue
+			}
+			statusf(cfg, "OK   %s\n", display)
+		}
+	}
+	return hadError
+}
+
+func writeDigest(target, period, key string, inputs []string, hash string, cfg Config) error {
+	start := time.Now()
+	joined := strings.Join(inputs, "\n\n")
+	lengthCategory := lengthCategoryFromRunes(len([]rune(joined)))
+	prompts := mergePrompts{
+		intermediate: buildDigestIntermediatePrompt,
+		final:        func(inputs []string) string { return buildDigestFinalPrompt(inputs, period, key, lengthCategory) },
+		unit:         "summary",
+		units:        "summaries",
+	}
+	summary, err := mergeTree(target, inputs, prompts, detectLanguage(joined), nil, cfg)
+	if err != nil {
+		return err
+	}
+	generatedAt := time.Now()
+	footer := buildSummaryFooter(generatedAt, generatedAt.Sub(start), len(inputs), "digest "+period, cfg)
+	output := fmt.Sprintf("# %s\n\n%s%s%s\n", key, stripThinkBlocks(summary), inputsMarker(hash), footer)
+	return writeFileAtomic(target, []byte(output), 0o644)
+}
+
+func buildDigestIntermediatePrompt(inputs []string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that condenses diary summaries while keeping the original language.\n\n")
+	b.WriteString("Task:\n- Each input below is the summary of one diary entry, labelled with its file and date, in chronological order.\n- Condense them into a single chronological account of this stretch of time.\n- Maintain the SAME LANGUAGE as the inputs (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the inputs.\n- Keep important names, dates, events and decisions.\n- Use 1–2 short paragraphs OR 3–6 bullet points.\n- Do NOT add headings, intro text, or any sections labelled 'Thinking'.\n\n")
+	b.WriteString("Input summaries:\n---\n")
+	for i, input := range inputs {
+		b.WriteString(fmt.Sprintf("Entry %d: %s\n\n", i+1, input))
+	}
+	b.WriteString("---\n\nReturn ONLY the condensed account, nothing else.\n")
+	return b.String()
+}
+
+func buildDigestFinalPrompt(inputs []string, period, key, lengthCategory string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that writes diary digests in the original language of the source texts.\n\n")
+	b.WriteString(fmt.Sprintf("Task:\n- You receive summaries of the diary entries of one %s period (%s), in chronological order.\n", period, key))
+	b.WriteString("- Write ONE digest of this period: what happened, what mattered, how things developed over time.\n- Name the most important events, people, decisions and moods, with their dates where known.\n- Maintain the SAME LANGUAGE as the inputs (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the inputs.\n- Be factual and do NOT add information that is not in the inputs.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
+	b.WriteString(finalOutputFormat)
+	b.WriteString("Do NOT add any footer or metadata lines; the system will append them.\n\n")
+	b.WriteString(fmt.Sprintf("Period content length category: %s.\n\n", lengthCategory))
+	b.WriteString("Input:\nThe following are the summaries of the period's entries:\n\n---\n")
+	for i, input := range inputs {
+		b.WriteString(fmt.Sprintf("Entry %d: %s\n\n", i+1, input))
+	}
+	b.WriteString("---\n\nNow produce ONLY the markdown digest as specified above.\nDo not add any intro text or explanations around it.\n")
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // YYYY-MM-DD, or "" when no date is found
+	}{
+		{"2024-03-12", "2024-03-12"},
+		{"## 2024-03-12 Montag", "2024-03-12"},
+		{"tagebuch_2024-03-12", "2024-03-12"},
+		{"20240312", "2024-03-12"},
+		{"notiz-20240312-abend", "2024-03-12"},
+		{"12.03.2024", "2024-03-12"},
+		{"## Dienstag, 5. 3. 2024", "2024-03-05"},
+		{"2024-02-30", ""},
+		{"31.04.2024", ""},
+		{"2024-02-29", "2024-02-29"},
+		{"2023-02-29", ""},
+		{"120240312", ""},
+		{"version 2024.3.12", ""},
+		{"kein Datum", ""},
+		// The ISO form wins over a German date later in the line.
+		{"2024-03-12 (nachgetragen am 14.03.2024)", "2024-03-12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := findDate(tt.in)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("findDate(%q) = %s, want no date", tt.in, got.Format("2006-01-02"))
+				}
+				return
+			}
+			if !ok || got.Format("2006-01-02") != tt.want {
+				t.Errorf("findDate(%q) = %s, %v, want %s", tt.in, got.Format("2006-01-02"), ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodKey(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		period string
+		date   string
+		want   string
+	}{
+		{periodWeekly, "2024-03-12", "2024-W11"},
+		{periodWeekly, "2024-01-01", "2024-W01"},
+		// ISO weeks belong to the year of their Thursday.
+		{periodWeekly, "2021-01-03", "2020-W53"},
+		{periodWeekly, "2024-12-30", "2025-W01"},
+		{periodMonthly, "2024-03-12", "2024-03"},
+		{periodMonthly, "2024-12-31", "2024-12"},
+		{periodYearly, "2024-03-12", "2024"},
+		{periodYearly, "2021-01-03", "2021"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period+" "+tt.date, func(t *testing.T) {
+			if got := periodKey(tt.period, date(tt.date)); got != tt.want {
+				t.Errorf("periodKey(%q, %s) = %q, want %q", tt.period, tt.date, got, tt.want)
+			}
+		})
+	}
+}
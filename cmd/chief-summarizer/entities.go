@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// entitiesReportName is the index of named entities written into the root
+// path by -entities.
+const entitiesReportName = "_entities.md"
+
+// Entity types accepted from the model.
+const (
+	entityPerson       = "person"
+	entityPlace        = "place"
+	entityOrganization = "organization"
+)
+
+var entityTypes = []string{entityPerson, entityPlace, entityOrganization}
+
+// entityTypeAliases maps other type names models tend to use to ours.
+var entityTypeAliases = map[string]string{
+	"people":       entityPerson,
+	"per":          entityPerson,
+	"location":     entityPlace,
+	"loc":          entityPlace,
+	"city":         entityPlace,
+	"country":      entityPlace,
+	"organisation": entityOrganization,
+	"org":          entityOrganization,
+	"company":      entityOrganization,
+}
+
+var entityHeadings = map[string]string{
+	entityPerson:       "Personen",
+	entityPlace:        "Orte",
+	entityOrganization: "Organisationen",
+}
+
+// Entity is a named person, place or organization mentioned in a document.
+type Entity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func buildEntityPrompt(chunk string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that extracts named entities from markdown notes.\n\n")
+	b.WriteString("Task:\n- List the people, places and organizations that are mentioned BY NAME in the excerpt below.\n- Use the spelling of the text; give people with their full name if the text does.\n- Do NOT include pronouns, generic terms (\"mein Chef\", \"die Stadt\"), dates or things.\n- If there are none, return an empty list.\n\n")
+	b.WriteString("Respond with JSON only, in exactly this form:\n{\"entities\": [{\"name\": \"Anna Schmidt\", \"type\": \"person\"}, {\"name\": \"Berlin\", \"type\": \"place\"}, {\"name\": \"Siemens\", \"type\": \"organization\"}]}\n\nExcerpt:\n---\n")
+	b.WriteString(chunk)
+	b.WriteString("\n---\n")
+	return b.String()
+}
+
+// extractEntities asks the model for the entities of every chunk and returns
+// them normalized and deduplicated for the whole document.
+func extractEntities(path string, chunks []string, cfg Config) []Entity {
+	perChunk := extractPerChunk(path, "entities", chunks, cfg, func(chunk string) ([]Entity, error) {
+		var result struct {
+			Entities []Entity `json:"entities"`
+		}
+		err := askJSON(buildEntityPrompt(chunk), &result, nil)
+		return result.Entities, err
+	})
+	var all []Entity
+	for _, entities := range perChunk {
+		all = append(all, entities...)
+	}
+	return normalizeEntities(all)
+}
+
+// normalizeEntities cleans up names and types, drops entries that are not
+// usable and merges duplicates that differ only in case or spacing.
+func normalizeEntities(entities []Entity) []Entity {
+	seen := make(map[string]bool)
+	var result []Entity
+	for _, e := range entities {
+		name := strings.Join(strings.Fields(e.Name), " ")
+		name = strings.Trim(name, ".,;:!?\"'()[]*_`")
+		kind := strings.ToLower(strings.TrimSpace(e.Type))
+		if alias, ok := entityTypeAliases[kind]; ok {
+			kind = alias
+		}
+		if len([]rune(name)) < 2 || !containsString(entityTypes, kind) {
+			continue
+		}
+		key := kind + "\x00" + strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, Entity{Name: name, Type: kind})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
+
+// readSummaryMeta reads the sidecar of the source at path.
+func readSummaryMeta(path string) (SummaryMeta, error) {
+	var meta SummaryMeta
+	data, err := os.ReadFile(summaryMetaFilename(path))
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal(data, &meta)
+	return meta, err
+}
+
+// markdownLink links from a report in the root path to the file at path.
+func markdownLink(path, root string) string {
+	display := displayPath(path, root)
+	target := (&url.URL{Path: filepath.ToSlash(display)}).EscapedPath()
+	return fmt.Sprintf("[%s](%s)", display, target)
+}
+
+// writeEntitiesReport collects the entities stored in the sidecars of plans
+// into _entities.md in the root path, grouped by type, with links to the
+// documents that mention them. It reports whether an error occurred.
+func writeEntitiesReport(plans []string, cfg Config) bool {
+	type entry struct {
+		name    string
+		sources []string
+	}
+	entries := make(map[string]*entry)
+	for _, path := range plans {
+		meta, err := readSummaryMeta(path)
+		if err != nil {
+			continue
+		}
+		for _, e := range meta.Entities {
+			key := e.Type + "\x00" + strings.ToLower(e.Name)
+			if entries[key] == nil {
+				entries[key] = &entry{name: e.Name}
+			}
+			entries[key].sources = append(entries[key].sources, path)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# Personen, Orte und Organisationen\n")
+	for _, kind := range entityTypes {
+		var keys []string
+		for key := range entries {
+			if strings.HasPrefix(key, kind+"\x00") {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "\n## %s\n\n", entityHeadings[kind])
+		for _, key := range keys {
+			e := entries[key]
+			sort.Strings(e.sources)
+			links := make([]string, len(e.sources))
+			for i, source := range e.sources {
+				links[i] = markdownLink(source, cfg.RootDir)
+			}
+			fmt.Fprintf(&b, "- **%s**: %s\n", e.name, strings.Join(links, ", "))
+		}
+	}
+	if len(entries) == 0 {
+		b.WriteString("\nNo entities found yet.\n")
+	}
+	return writeReport(filepath.Join(cfg.RootDir, entitiesReportName), b.String(), cfg)
+}
+
+// writeReport writes a generated report into the root path unless it is
+// unchanged. It reports whether an error occurred.
+func writeReport(path, content string, cfg Config) bool {
+	display := displayPath(path, cfg.RootDir)
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		if cfg.Verbose {
+			statusf(cfg, "SKIP %s (unchanged)\n", display)
+		}
+		return false
+	}
+	if cfg.DryRun {
+		statusf(cfg, "DRY  %s (would update report)\n", display)
+		return false
+	}
+	if err := writeFileAtomic(path, []byte(content), 0o644); err != nil {
+		errorf("ERR  %s (write report: %v)\n", display, err)
+		return true
+	}
+	statusf(cfg, "OK   %s\n", display)
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// jsonAttempts is how often a structured-output prompt is sent before an
+// unusable answer is given up on.
+const jsonAttempts = 2
+
+// decodeJSONResponse decodes the JSON object in a model response into v,
+// ignoring think blocks, code fences and text around the object.
+func decodeJSONResponse(resp string, v any) error {
+	text := stripThinkBlocks(resp)
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return errors.New("no JSON object in response")
+	}
+	return json.Unmarshal([]byte(text[start:end+1]), v)
+}
+
+// askJSON sends a structured-output prompt and decodes the answer into v,
+// asking again if the answer is not valid JSON or check rejects it. check may
+// be nil.
+func askJSON(prompt string, v any, check func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < jsonAttempts; attempt++ {
+		resp, err := callOllamaJSON(prompt)
+		if err != nil {
+			return err
+		}
+		if err := decodeJSONResponse(resp, v); err != nil {
+			lastErr = fmt.Errorf("invalid JSON: %w", err)
+			continue
+		}
+		if check != nil {
+			if err := check(); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// extractPerChunk runs extract for every chunk, spread over the Ollama hosts.
+// A chunk whose extraction fails is reported and left empty so one bad answer
+// does not cost the whole document.
+func extractPerChunk[T any](path, what string, chunks []string, cfg Config, extract func(chunk string) (T, error)) []T {
+	results := make([]T, len(chunks))
+	display := displayPath(path, cfg.RootDir)
+	statusf(cfg, "EXTR %s (%s, %d chunks)\n", display, what, len(chunks))
+	runParallel(len(chunks), ollamaPool.capacity(), func(i int) error {
+		result, err := extract(chunks[i])
+		if err != nil {
+			errorf("WARN %s (%s extraction failed for chunk %d: %v)\n", display, what, i+1, err)
+			return nil
+		}
+		results[i] = result
+		return nil
+	})
+	return results
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Supported values for -fact-check.
+const (
+	factCheckOff     = "off"
+	factCheckFlag    = "flag"
+	factCheckSection = "section"
+)
+
+var factCheckModes = []string{factCheckOff, factCheckFlag, factCheckSection}
+
+// UnsupportedFact is a date, number or name from a summary that could not be
+// found in the source document.
+type UnsupportedFact struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+var (
+	isoDatePattern    = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	dottedDatePattern = regexp.MustCompile(`\b\d{1,2}\.\s?\d{1,2}\.\s?(\d{2}|\d{4})\b`)
+	numberPattern     = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+	digitsPattern     = regexp.MustCompile(`\d+`)
+	capitalizedWord   = regexp.MustCompile(`\p{Lu}[\p{L}\p{M}'’-]+`)
+)
+
+// commonCapitalized lists capitalized words that are not worth checking:
+// headings, pronouns, months, weekdays and times of day in German and English.
+var commonCapitalized = map[string]bool{
+	"ultra-kurzfassung": true, "ausführliche": true, "zusammenfassung": true,
+	"ich": true, "wir": true, "sie": true, "er": true, "es": true, "der": true, "die": true, "das": true,
+	"the": true, "and": true, "this": true, "i": true,
+	"januar": true, "februar": true, "märz": true, "april": true, "mai": true, "juni": true, "juli": true,
+	"august": true, "september": true, "oktober": true, "november": true, "dezember": true,
+	"january": true, "february": true, "march": true, "may": true, "june": true, "july": true,
+	"october": true, "december": true,
+	"montag": true, "dienstag": true, "mittwoch": true, "donnerstag": true, "freitag": true, "samstag": true, "sonntag": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+	"morgen": true, "vormittag": true, "mittag": true, "nachmittag": true, "abend": true, "nacht": true,
+	"anfang": true, "ende": true, "woche": true, "wochenende": true,
+}
+
+// germanNounMarkers are words that introduce a noun phrase in German. A
+// capitalized word after one of them (and any adjectives) is a common noun,
+// since German capitalizes all nouns, not only names.
+var germanNounMarkers = map[string]bool{
+	"der": true, "die": true, "das": true, "den": true, "dem": true, "des": true,
+	"ein": true, "eine": true, "einen": true, "einem": true, "einer": true, "eines": true,
+	"kein": true, "keine": true, "keinen": true, "keinem": true, "keiner": true,
+	"am": true, "im": true, "vom": true, "zum": true, "zur": true, "beim": true, "ins": true, "ans": true, "aufs": true,
+	"mein": true, "meine": true, "meinen": true, "meinem": true, "meiner": true, "meines": true,
+	"unser": true, "unsere": true, "unseren": true, "unserem": true, "unserer": true,
+	"dieser": true, "diese": true, "diesen": true, "diesem": true, "dieses": true,
+	"jeder": true, "jede": true, "jeden": true, "jedem": true, "viel": true, "viele": true, "vielen": true,
+	"etwas": true, "mehr": true, "wenig": true, "wenige": true, "einige": true, "einigen": true,
+}
+
+// germanNounSuffixes end common nouns, never names.
+var germanNounSuffixes = []string{"ung", "heit", "keit", "schaft", "tion", "ität", "nis", "tum", "ment", "chen", "lein", "ismus"}
+
+// checkFacts extracts dates, numbers and capitalized names from summary and
+// returns those that do not appear in source. Heading lines are ignored.
+// Unless the source is English, capitalized words are only taken for names
+// when they do not look like German common nouns.
+func checkFacts(summary, source string) []UnsupportedFact {
+	german := detectLanguage(source) != "en"
+	var body strings.Builder
+	for _, line := range strings.Split(summary, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	text := body.String()
+
+	sourceLower := strings.ToLower(source)
+	sourceNumbers := make(map[string]bool)
+	for _, n := range numberPattern.FindAllString(source, -1) {
+		sourceNumbers[normalizeNumber(n)] = true
+	}
+	// numberPattern takes 12.03.2024 as one number; add its parts too.
+	for _, date := range dottedDatePattern.FindAllString(source, -1) {
+		for _, part := range digitsPattern.FindAllString(date, -1) {
+			sourceNumbers[normalizeNumber(part)] = true
+		}
+	}
+	sourceWords := wordPattern.FindAllString(sourceLower, -1)
+
+	var facts []UnsupportedFact
+	seen := make(map[string]bool)
+	add := func(kind, value string) {
+		key := kind + "\x00" + value
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		facts = append(facts, UnsupportedFact{Kind: kind, Value: value})
+	}
+
+	// Dates are checked by their numeric parts so 12.03.2024 and 2024-03-12 match.
+	for _, pattern := range []*regexp.Regexp{isoDatePattern, dottedDatePattern} {
+		for _, date := range pattern.FindAllString(text, -1) {
+			for _, part := range numberPattern.FindAllString(date, -1) {
+				if !sourceNumbers[normalizeNumber(part)] {
+					add("date", date)
+					break
+				}
+			}
+		}
+		text = pattern.ReplaceAllString(text, " ")
+	}
+
+	for _, n := range numberPattern.FindAllString(text, -1) {
+		if !sourceNumbers[normalizeNumber(n)] {
+			add("number", n)
+		}
+	}
+
+	for _, loc := range capitalizedWord.FindAllStringIndex(text, -1) {
+		word := text[loc[0]:loc[1]]
+		lower := strings.ToLower(word)
+		if commonCapitalized[lower] || len([]rune(word)) < 3 || atSentenceStart(text, loc[0]) {
+			continue
+		}
+		if german && (followsNounMarker(text, loc[0]) || hasGermanNounSuffix(lower)) {
+			continue
+		}
+		if !wordSupported(lower, sourceLower, sourceWords) {
+			add("name", word)
+		}
+	}
+	return facts
+}
+
+// normalizeNumber strips leading zeros and thousands separators so "03",
+// "3" and "1.000"/"1000" compare equal.
+func normalizeNumber(n string) string {
+	n = strings.NewReplacer(".", "", ",", "").Replace(n)
+	n = strings.TrimLeft(n, "0")
+	if n == "" {
+		return "0"
+	}
+	return n
+}
+
+// atSentenceStart reports whether the word at offset begins a sentence or a
+// list item, where capitalization says nothing about proper nouns.
+func atSentenceStart(text string, offset int) bool {
+	prefix := strings.TrimRightFunc(text[:offset], unicode.IsSpace)
+	if prefix == "" || strings.Contains(text[len(prefix):offset], "\n") {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(prefix)
+	switch last {
+	case '.', '!', '?', ':', '-', '*', '"', '„', '(':
+		return true
+	}
+	return false
+}
+
+// followsNounMarker reports whether the word at offset follows an article or
+// similar word, possibly with lowercase adjectives in between ("am Ende",
+// "das neue Projekt").
+func followsNounMarker(text string, offset int) bool {
+	words := wordPattern.FindAllString(text[:offset], -1)
+	for i := len(words) - 1; i >= 0 && i >= len(words)-3; i-- {
+		word := words[i]
+		if germanNounMarkers[strings.ToLower(word)] {
+			return true
+		}
+		if r, _ := utf8.DecodeRuneInString(word); !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return false
+}
+
+func hasGermanNounSuffix(word string) bool {
+	for _, suffix := range germanNounSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// wordSupported matches inflected forms loosely: "Annas" is supported by
+// "Anna" and "Berliner" by "Berlin".
+func wordSupported(word, sourceLower string, sourceWords []string) bool {
+	if strings.Contains(sourceLower, word) {
+		return true
+	}
+	runes := []rune(word)
+	stem := len(runes) - 3
+	if stem < 4 {
+		stem = 4
+	}
+	if stem >= len(runes) {
+		return false
+	}
+	prefix := string(runes[:stem])
+	for _, w := range sourceWords {
+		if strings.HasPrefix(w, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildFactWarningSection renders unsupported facts as a markdown section that
+// can be appended to the summary.
+func buildFactWarningSection(facts []UnsupportedFact) string {
+	var b strings.Builder
+	b.WriteString("\n\n## Hinweis: Nicht belegte Angaben\n")
+	b.WriteString("Die folgenden Angaben wurden im Originaldokument nicht gefunden und sollten geprüft werden:\n")
+	for _, f := range facts {
+		b.WriteString(fmt.Sprintf("- %s (%s)\n", f.Value, f.Kind))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatFacts(facts []UnsupportedFact) string {
+	values := make([]string, 0, len(facts))
+	for _, f := range facts {
+		values = append(values, f.Value)
+	}
+	return strings.Join(values, ", ")
+}
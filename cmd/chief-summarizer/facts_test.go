@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckFacts(t *testing.T) {
+	germanSource := "Heute war ich mit Anna in Berlin. Wir haben lange über die Firma geredet und ich bin müde nach Hause gegangen. Am 12.03.2024 war ich wieder im Büro und habe 3 Stunden gearbeitet, das war nicht schön."
+	englishSource := "Today I met Anna in Berlin. We talked about the company and it was a long day, and I went home tired. On 2024-03-12 I was back at the office for 3 hours."
+
+	tests := []struct {
+		name    string
+		summary string
+		source  string
+		want    []UnsupportedFact
+	}{
+		{
+			name:    "German common nouns are not names",
+			summary: "Ich traf Anna in Berlin. Am Ende des Tages war ich müde, am Morgen ging es mit dem neuen Projekt weiter und nach der Arbeit ging ich heim. Die Besprechung war lang.",
+			source:  germanSource,
+		},
+		{
+			name:    "German invented name is flagged",
+			summary: "Ich traf Anna und Thomas in Berlin.",
+			source:  germanSource,
+			want:    []UnsupportedFact{{Kind: "name", Value: "Thomas"}},
+		},
+		{
+			name:    "dates and numbers are compared by value",
+			summary: "Am 2024-03-12 war ich 3 Stunden im Büro, nicht 5.",
+			source:  germanSource,
+			want:    []UnsupportedFact{{Kind: "number", Value: "5"}},
+		},
+		{
+			name:    "English capitalized words mid-sentence are names",
+			summary: "I met Anna and Thomas in Berlin.",
+			source:  englishSource,
+			want:    []UnsupportedFact{{Kind: "name", Value: "Thomas"}},
+		},
+		{
+			name:    "inflected names are supported",
+			summary: "Ich habe Annas Wohnung in Berlin besucht.",
+			source:  germanSource,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkFacts(tt.summary, tt.source)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkFacts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// chiefIgnoreFile holds gitignore-style rules for Chief Summarizer only.
+	chiefIgnoreFile = ".chiefignore"
+	gitIgnoreFile   = ".gitignore"
+	// regexPrefix marks an -include pattern as a regular expression.
+	regexPrefix = "re:"
+)
+
+// ignoreRule is one line of an ignore file, matched against paths relative to
+// the directory that holds the file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	dir     string
+	source  string
+}
+
+// pathFilter decides which paths a walk of cfg.RootDir skips: -exclude
+// regexes, .chiefignore files (and .gitignore with -gitignore) in any
+// directory, and -include patterns for source files.
+type pathFilter struct {
+	cfg         Config
+	ignoreFiles []string
+	rules       map[string][]ignoreRule
+}
+
+func newPathFilter(cfg Config) *pathFilter {
+	f := &pathFilter{cfg: cfg, rules: make(map[string][]ignoreRule)}
+	if cfg.UseGitignore {
+		f.ignoreFiles = append(f.ignoreFiles, gitIgnoreFile)
+	}
+	f.ignoreFiles = append(f.ignoreFiles, chiefIgnoreFile)
+	return f
+}
+
+// excluded returns why path should be skipped, or "" to keep it.
+func (f *pathFilter) excluded(path string, isDir bool) string {
+	if matchesExclude(path, f.cfg.RootDir, f.cfg.Excludes) {
+		if isDir {
+			return "directory excluded"
+		}
+		return "excluded by pattern"
+	}
+	if filepath.Clean(path) == filepath.Clean(f.cfg.RootDir) {
+		return ""
+	}
+	ignoredBy := ""
+	for _, rule := range f.rulesFor(filepath.Dir(path)) {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.dir, path)
+		if err != nil || !rule.re.MatchString(filepath.ToSlash(rel)) {
+			continue
+		}
+		ignoredBy = rule.source
+		if rule.negate {
+			ignoredBy = ""
+		}
+	}
+	if ignoredBy != "" {
+		return "ignored by " + ignoredBy
+	}
+	return ""
+}
+
+// included reports whether a source file matches -include; every file does
+// when no include patterns are set.
+func (f *pathFilter) included(path string) bool {
+	if len(f.cfg.Includes) == 0 {
+		return true
+	}
+	rel, err := filepath.Rel(f.cfg.RootDir, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	for _, re := range f.cfg.Includes {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesFor returns the ignore rules that apply inside dir: those of its
+// parents up to the root followed by its own, so later rules win.
+func (f *pathFilter) rulesFor(dir string) []ignoreRule {
+	dir = filepath.Clean(dir)
+	if rules, ok := f.rules[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	if parent := filepath.Dir(dir); dir != filepath.Clean(f.cfg.RootDir) && parent != dir {
+		rules = append(rules, f.rulesFor(parent)...)
+	}
+	for _, name := range f.ignoreFiles {
+		path := filepath.Join(dir, name)
+		own, err := readIgnoreFile(path, dir, displayPath(path, f.cfg.RootDir))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errorf("WARN %s unreadable: %v\n", displayPath(path, f.cfg.RootDir), err)
+		}
+		rules = append(rules, own...)
+	}
+	f.rules[dir] = rules
+	return rules
+}
+
+// readIgnoreFile parses a file with gitignore syntax: blank lines and #
+// comments are skipped, ! negates, a trailing / matches only directories and
+// a pattern containing / is anchored to dir.
+func readIgnoreFile(path, dir, source string) ([]ignoreRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasSuffix(line, `\`) {
+			line += " "
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{dir: dir, source: source}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		prefix := "^(?:.*/)?"
+		if strings.Contains(line, "/") {
+			prefix = "^"
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile(prefix + globToRegexp(line) + "$")
+		if err != nil {
+			errorf("WARN %s:%d: invalid pattern: %v\n", source, lineNo, err)
+			continue
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// globToRegexp translates a glob with gitignore wildcards to a regular
+// expression body: * and ? stay within one path segment, ** spans segments.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compileInclude turns an -include pattern into a regular expression matched
+// against slash-separated paths relative to the root. Patterns starting with
+// "re:" are regular expressions; anything else is a glob, matched against the
+// file name unless it contains a /.
+func compileInclude(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		return regexp.Compile(expr)
+	}
+	prefix := "^(?:.*/)?"
+	if strings.Contains(pattern, "/") {
+		prefix = "^"
+	}
+	re, err := regexp.Compile(prefix + globToRegexp(strings.TrimPrefix(pattern, "/")) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob: %w", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*.md", "notes.md", true},
+		{"*.md", "notes.txt", false},
+		{"*.md", "2024/notes.md", false},
+		{"?.md", "a.md", true},
+		{"?.md", "ab.md", false},
+		{"**/draft.md", "draft.md", true},
+		{"**/draft.md", "a/b/draft.md", true},
+		{"**/draft.md", "a/b/final.md", false},
+		{"archiv/**", "archiv/2020/x.md", true},
+		{"archiv/**", "other/x.md", false},
+		{"a/**/b.md", "a/b.md", true},
+		{"a/**/b.md", "a/x/y/b.md", true},
+		{"[0-9]*.md", "2024.md", true},
+		{"[0-9]*.md", "notes.md", false},
+		{"[!0-9]*.md", "notes.md", true},
+		{"[!0-9]*.md", "2024.md", false},
+		{"notes.md", "notesxmd", false},
+		{`\*.md`, "*.md", true},
+		{`\*.md`, "a.md", false},
+		{"[abc", "[abc", true},
+		{"(privat)+.md", "(privat)+.md", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			re, err := regexp.Compile("^" + globToRegexp(tt.glob) + "$")
+			if err != nil {
+				t.Fatalf("globToRegexp(%q) = %q does not compile: %v", tt.glob, globToRegexp(tt.glob), err)
+			}
+			if got := re.MatchString(tt.path); got != tt.match {
+				t.Errorf("%q (%s) matches %q = %v, want %v", tt.glob, re, tt.path, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, chiefIgnoreFile)
+	content := "# Kommentar\n\n*.tmp.md\nprivat/\n/entwurf.md\n!wichtig.tmp.md\ndocs/*.md\n\\#hash.md\ntrailing.md   \n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := readIgnoreFile(path, dir, chiefIgnoreFile)
+	if err != nil {
+		t.Fatalf("readIgnoreFile() error = %v", err)
+	}
+	if len(rules) != 7 {
+		t.Fatalf("got %d rules, want 7", len(rules))
+	}
+
+	// Later rules win, as in excluded.
+	ignored := func(rel string, isDir bool) bool {
+		result := false
+		for _, rule := range rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(rel) {
+				result = !rule.negate
+			}
+		}
+		return result
+	}
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"a.tmp.md", false, true},
+		{"sub/a.tmp.md", false, true},
+		{"wichtig.tmp.md", false, false},
+		{"privat", true, true},
+		{"sub/privat", true, true},
+		{"privat", false, false},
+		{"entwurf.md", false, true},
+		{"sub/entwurf.md", false, false},
+		{"docs/a.md", false, true},
+		{"docs/sub/a.md", false, false},
+		{"#hash.md", false, true},
+		{"trailing.md", false, true},
+		{"notes.md", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := ignored(tt.rel, tt.isDir); got != tt.want {
+				t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadIgnoreFileMissing(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := readIgnoreFile(filepath.Join(dir, chiefIgnoreFile), dir, chiefIgnoreFile); !os.IsNotExist(err) {
+		t.Errorf("readIgnoreFile() error = %v, want a not-exist error", err)
+	}
+}
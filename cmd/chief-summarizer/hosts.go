@@ -0,0 +1,339 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OllamaHost is one endpoint from ollama.hosts in the config file.
+type OllamaHost struct {
+	URL            string `yaml:"url"`
+	Weight         int    `yaml:"weight"`
+	MaxConcurrency int    `yaml:"max_concurrency"`
+}
+
+// ollamaPool spreads generate requests over the configured hosts; set up in main.
+var ollamaPool *hostPool
+
+type poolHost struct {
+	OllamaHost
+	inflight  int
+	current   int
+	healthy   bool
+	downUntil time.Time
+}
+
+// hostPool picks hosts by smooth weighted round-robin among those that are
+// healthy and below their concurrency limit. A host that fails its /api/tags
+// health check or a request is taken out of rotation for cooldown and checked
+// again before it is used next. Every host serves the same model, so each
+// summary records the model that actually wrote it.
+type hostPool struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	hosts    []*poolHost
+	cooldown time.Duration
+	cfg      Config
+	model    string
+}
+
+func newHostPool(specs []OllamaHost, cooldown time.Duration, cfg Config) *hostPool {
+	p := &hostPool{cooldown: cooldown, cfg: cfg}
+	p.cond = sync.NewCond(&p.mu)
+	for _, spec := range specs {
+		if spec.Weight <= 0 {
+			spec.Weight = 1
+		}
+		if spec.MaxConcurrency <= 0 {
+			spec.MaxConcurrency = 1
+		}
+		p.hosts = append(p.hosts, &poolHost{OllamaHost: spec})
+	}
+	return p
+}
+
+// checkAll health-checks every host and resolves the model of the pool with
+// chooseModel, preferring models installed on all reachable hosts. Reachable
+// hosts without the chosen model stay out of rotation with a warning; hosts
+// that cannot be reached are assumed to have it and checked again when used.
+func (p *hostPool) checkAll() (string, error) {
+	var common, all []string
+	var reachable, failures []string
+	installed := make(map[*poolHost][]string)
+	for _, h := range p.hosts {
+		available, err := listAvailableModels(h.URL)
+		if err != nil {
+			if p.cfg.Verbose {
+				errorf("WARN unable to query models from %s: %v\n", h.URL, err)
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", h.URL, err))
+			p.markDown(h)
+			continue
+		}
+		h.healthy = true
+		installed[h] = available
+		if len(reachable) == 0 {
+			common = available
+		} else {
+			common = intersectModels(common, available)
+		}
+		for _, name := range available {
+			if !containsString(all, name) {
+				all = append(all, name)
+			}
+		}
+		reachable = append(reachable, h.URL)
+	}
+
+	source := strings.Join(reachable, ", ")
+	candidates := common
+	if len(reachable) > 1 {
+		source = "every host"
+		if len(common) == 0 {
+			source = "any host"
+			candidates = all
+		}
+	}
+	model, err := chooseModel(p.cfg, source, candidates)
+	if err != nil {
+		if len(reachable) == 0 && len(failures) > 0 {
+			return "", fmt.Errorf("%w, and no host could be asked for its models (%s)", err, strings.Join(failures, "; "))
+		}
+		return "", err
+	}
+	p.model = model
+	if p.cfg.Model == "" {
+		for _, h := range p.hosts {
+			if available, ok := installed[h]; ok && !containsString(available, model) {
+				errorf("WARN host %s does not have model %s installed; leaving it out of rotation\n", h.URL, model)
+				p.markDown(h)
+			}
+		}
+	}
+
+	if len(p.hosts) > 1 && !p.cfg.Quiet {
+		for _, h := range p.hosts {
+			state := "up"
+			if !h.healthy {
+				state = "down"
+			}
+			fmt.Printf("Host %s: weight %d, max concurrency %d (%s)\n", h.URL, h.Weight, h.MaxConcurrency, state)
+		}
+	}
+	return model, nil
+}
+
+// intersectModels returns the models of a that are also in b.
+func intersectModels(a, b []string) []string {
+	var both []string
+	for _, name := range a {
+		if containsString(b, name) {
+			both = append(both, name)
+		}
+	}
+	return both
+}
+
+// errModelMissing marks a host that is reachable but lacks the pool's model.
+var errModelMissing = errors.New("model is not installed")
+
+// check queries /api/tags on h and marks it healthy or down. A host without
+// the model resolved for the pool counts as down.
+func (p *hostPool) check(h *poolHost) error {
+	available, err := listAvailableModels(h.URL)
+	if err == nil && p.cfg.Model == "" && !containsString(available, p.model) {
+		err = fmt.Errorf("%w: %s on %s", errModelMissing, p.model, h.URL)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.markDown(h)
+		return err
+	}
+	h.healthy = true
+	return nil
+}
+
+// markDown takes h out of rotation for the cooldown.
+func (p *hostPool) markDown(h *poolHost) {
+	h.healthy = false
+	h.downUntil = time.Now().Add(p.cooldown)
+}
+
+// capacity is the number of requests the pool can run at once.
+func (p *hostPool) capacity() int {
+	total := 0
+	for _, h := range p.hosts {
+		total += h.MaxConcurrency
+	}
+	return total
+}
+
+// acquire reserves a slot on the next host. Hosts whose cooldown expired are
+// health-checked first. If every host is down, the free one that recovers
+// soonest is used without a check so a run never stalls on a cooldown.
+func (p *hostPool) acquire() (*poolHost, error) {
+	p.mu.Lock()
+	for {
+		now := time.Now()
+		h := p.pick(now)
+		if h == nil {
+			p.cond.Wait()
+			continue
+		}
+		h.inflight++
+		recheck := !h.healthy && !now.Before(h.downUntil)
+		p.mu.Unlock()
+		if !recheck {
+			return h, nil
+		}
+		if err := p.check(h); err != nil {
+			p.release(h, nil)
+			return nil, err
+		}
+		return h, nil
+	}
+}
+
+func (p *hostPool) pick(now time.Time) *poolHost {
+	var best, fallback *poolHost
+	total := 0
+	available := false
+	for _, h := range p.hosts {
+		down := !h.healthy && now.Before(h.downUntil)
+		available = available || !down
+		if h.inflight >= h.MaxConcurrency {
+			continue
+		}
+		if down {
+			if fallback == nil || h.downUntil.Before(fallback.downUntil) {
+				fallback = h
+			}
+			continue
+		}
+		h.current += h.Weight
+		total += h.Weight
+		if best == nil || h.current > best.current {
+			best = h
+		}
+	}
+	if best == nil {
+		if available {
+			return nil
+		}
+		return fallback
+	}
+	best.current -= total
+	return best
+}
+
+// release frees the slot taken by acquire and takes h out of rotation if err
+// shows the host itself is unavailable.
+func (p *hostPool) release(h *poolHost, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h.inflight--
+	if hostFailure(err) {
+		if h.healthy && len(p.hosts) > 1 {
+			errorf("WARN host %s out of rotation for %s: %v\n", h.URL, p.cooldown, err)
+		}
+		p.markDown(h)
+	}
+	p.cond.Broadcast()
+}
+
+// generate sends prompt to a host from the pool, failing over to another host
+// when the chosen one is unavailable.
+func (p *hostPool) generate(prompt, format string) (string, error) {
+	var lastErr error
+	for range p.hosts {
+		h, err := p.acquire()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := generateOllama(h.URL, p.model, prompt, format)
+		p.release(h, err)
+		if !hostFailure(err) {
+			return resp, err
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// embed is generate for embeddings: it sends inputs to a host from the pool,
+// failing over to another host when the chosen one is unavailable.
+func (p *hostPool) embed(model string, inputs []string) ([][]float32, error) {
+	var lastErr error
+	for range p.hosts {
+		h, err := p.acquire()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		vectors, err := embedOllama(h.URL, model, inputs)
+		p.release(h, err)
+		if !hostFailure(err) {
+			return vectors, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// hostFailure reports whether err means the host could not serve the request
+// (connection problems, timeouts, 5xx responses, a missing model) rather than
+// a bad answer.
+func hostFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	var statusErr *ollamaStatusError
+	return errors.As(err, &urlErr) || errors.Is(err, errModelMissing) ||
+		(errors.As(err, &statusErr) && statusErr.StatusCode >= 500)
+}
+
+// runParallel calls fn for 0..n-1 with at most workers calls in flight and
+// returns the first error. No new calls start once one has failed.
+func runParallel(n, workers int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		next     int
+		firstErr error
+	)
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if firstErr != nil || next >= n {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+				if err := fn(i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// embedBatchSize is how many passages are embedded per request.
+const embedBatchSize = 16
+
+// embeddingIndex holds the embeddings of passages, keyed by the hash of their
+// text, so unchanged passages are never embedded twice.
+type embeddingIndex struct {
+	Model   string               `json:"model"`
+	Vectors map[string][]float32 `json:"vectors"`
+}
+
+// indexPath returns the location of the embedding index of cfg.
+func indexPath(cfg Config) string {
+	if filepath.IsAbs(cfg.IndexFile) {
+		return cfg.IndexFile
+	}
+	return filepath.Join(cfg.RootDir, cfg.IndexFile)
+}
+
+// loadEmbeddingIndex reads the index at path. A missing index, or one built
+// with another model, yields an empty index for model.
+func loadEmbeddingIndex(path, model string) (*embeddingIndex, error) {
+	idx := &embeddingIndex{Model: model, Vectors: make(map[string][]float32)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return idx, err
+	}
+	var stored embeddingIndex
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return idx, err
+	}
+	if stored.Model == model && stored.Vectors != nil {
+		idx.Vectors = stored.Vectors
+	}
+	return idx, nil
+}
+
+func (idx *embeddingIndex) save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// updateEmbeddingIndex embeds the summaries and source chunks of plans that
+// are not in the index yet and drops the vectors of passages that no longer
+// exist. It reports whether an error occurred.
+func updateEmbeddingIndex(plans []string, cfg Config) bool {
+	path := indexPath(cfg)
+	display := displayPath(path, cfg.RootDir)
+	idx, err := loadEmbeddingIndex(path, cfg.EmbedModel)
+	if err != nil {
+		errorf("WARN %s (unreadable, rebuilding: %v)\n", display, err)
+	}
+
+	current := make(map[string]bool)
+	var hashes, texts []string
+	for _, p := range collectPassages(plans, cfg) {
+		hash := hashBytes([]byte(p.text))
+		if current[hash] {
+			continue
+		}
+		current[hash] = true
+		if idx.Vectors[hash] == nil {
+			hashes = append(hashes, hash)
+			texts = append(texts, p.text)
+		}
+	}
+	removed := 0
+	for hash := range idx.Vectors {
+		if !current[hash] {
+			delete(idx.Vectors, hash)
+			removed++
+		}
+	}
+	if len(texts) == 0 && removed == 0 {
+		if cfg.Verbose {
+			statusf(cfg, "SKIP %s (index up to date)\n", display)
+		}
+		return false
+	}
+	if cfg.DryRun {
+		statusf(cfg, "DRY  %s (would embed %d passages, drop %d)\n", display, len(texts), removed)
+		return false
+	}
+
+	hadError := false
+	if len(texts) > 0 {
+		statusf(cfg, "EMBD %s (%d passages with %s)\n", display, len(texts), cfg.EmbedModel)
+		var mu sync.Mutex
+		batches := (len(texts) + embedBatchSize - 1) / embedBatchSize
+		runParallel(batches, ollamaPool.capacity(), func(i int) error {
+			start := i * embedBatchSize
+			end := min(start+embedBatchSize, len(texts))
+			vectors, err := callOllamaEmbed(cfg.EmbedModel, texts[start:end])
+			if err != nil {
+				errorf("ERR  %s (embedding batch %d/%d: %v)\n", display, i+1, batches, err)
+				mu.Lock()
+				hadError = true
+				mu.Unlock()
+				return nil
+			}
+			mu.Lock()
+			for j, vector := range vectors {
+				idx.Vectors[hashes[start+j]] = vector
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	// Keep what was embedded even if a batch failed; the rest follows next run.
+	if err := idx.save(path); err != nil {
+		errorf("ERR  %s (write index: %v)\n", display, err)
+		return true
+	}
+	statusf(cfg, "OK   %s (%d passages, %d removed)\n", display, len(idx.Vectors), removed)
+	return hadError
+}
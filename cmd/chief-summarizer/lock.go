@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errLockHeld is returned by tryLock when another process holds the lock.
+var errLockHeld = errors.New("lock held by another process")
+
+const lockPollInterval = 500 * time.Millisecond
+
+var unsafeLockNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// lockPathFor returns the lock file for a run. Runs on different roots get
+// different locks, so they can proceed in parallel; -lock-name overrides the
+// root-derived name, e.g. to serialize runs that share one Ollama host.
+func lockPathFor(cfg Config) string {
+	name := unsafeLockNameChars.ReplaceAllString(cfg.LockName, "_")
+	if name == "" {
+		root := cfg.RootDir
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		sum := sha256.Sum256([]byte(root))
+		name = hex.EncodeToString(sum[:])[:12]
+	}
+	return filepath.Join(os.TempDir(), "chief-summarizer-"+name+".lock")
+}
+
+// acquireLock takes the lock for cfg's root. If another run holds it, it
+// polls for up to cfg.LockWait before giving up.
+func acquireLock(cfg Config) (*os.File, error) {
+	path := lockPathFor(cfg)
+	deadline := time.Now().Add(cfg.LockWait)
+	waiting := false
+	for {
+		lockFile, err := tryLock(path)
+		if err == nil {
+			writeLockInfo(lockFile, cfg.RootDir)
+			return lockFile, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, err
+		}
+		if cfg.LockWait <= 0 || time.Now().After(deadline) {
+			return nil, lockHeldError(path)
+		}
+		if !waiting {
+			statusf(cfg, "WAIT %s (waiting up to %s for the running instance)\n", cfg.RootDir, formatDuration(cfg.LockWait))
+			waiting = true
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// writeLockInfo records the owner of the lock for diagnostics.
+func writeLockInfo(lockFile *os.File, root string) {
+	lockFile.Truncate(0)
+	lockFile.Seek(0, 0)
+	fmt.Fprintf(lockFile, "%d\n%s\n%s\n", os.Getpid(), root, time.Now().Format(time.RFC3339))
+	lockFile.Sync()
+}
+
+// lockHeldError builds a helpful error from the owner info in the lock file.
+func lockHeldError(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return fmt.Errorf("another instance is already running (lock %s)", path)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return fmt.Errorf("another instance is already running (lock %s)", path)
+	}
+	owner := fmt.Sprintf("PID: %d", pid)
+	if len(lines) >= 3 {
+		owner = fmt.Sprintf("PID: %d, root: %s, since: %s", pid, lines[1], lines[2])
+	}
+	if !processAlive(pid) {
+		return fmt.Errorf("lock %s is held but its recorded owner (%s) is not running here; it may belong to another host or container", path, owner)
+	}
+	return fmt.Errorf("another instance is already running (%s)", owner)
+}
@@ -6,9 +6,6 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
 	"syscall"
 	"unsafe"
 )
@@ -22,12 +19,14 @@ var (
 const (
 	LOCKFILE_EXCLUSIVE_LOCK   = 0x00000002
 	LOCKFILE_FAIL_IMMEDIATELY = 0x00000001
+	ERROR_LOCK_VIOLATION      = 33
+
+	// lockByteOffset places the locked byte far beyond the owner info written
+	// to the file, so other processes can still read who holds the lock.
+	lockByteOffset = 0x7fffffff
 )
 
-func acquireLock() (*os.File, error) {
-	// Use system temp directory for lock file
-	lockPath := filepath.Join(os.TempDir(), "chief-summarizer.lock")
-	
+func tryLock(lockPath string) (*os.File, error) {
 	// Try to open or create the lock file
 	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
@@ -35,9 +34,9 @@ func acquireLock() (*os.File, error) {
 	}
 
 	// Try to acquire exclusive lock using Windows LockFileEx
-	var overlapped syscall.Overlapped
+	overlapped := syscall.Overlapped{Offset: lockByteOffset}
 	handle := syscall.Handle(lockFile.Fd())
-	
+
 	ret, _, err := procLockFileEx.Call(
 		uintptr(handle),
 		uintptr(LOCKFILE_EXCLUSIVE_LOCK|LOCKFILE_FAIL_IMMEDIATELY),
@@ -45,32 +44,30 @@ func acquireLock() (*os.File, error) {
 		1, 0, // Lock 1 byte
 		uintptr(unsafe.Pointer(&overlapped)),
 	)
-	
+
 	if ret == 0 {
 		lockFile.Close()
-		
-		// Try to read PID from lock file to show helpful message
-		if data, readErr := os.ReadFile(lockPath); readErr == nil && len(data) > 0 {
-			if pid, parseErr := strconv.Atoi(strings.TrimSpace(string(data))); parseErr == nil {
-				return nil, fmt.Errorf("another instance is already running (PID: %d)", pid)
-			}
+		if errno, ok := err.(syscall.Errno); ok && errno == ERROR_LOCK_VIOLATION {
+			return nil, errLockHeld
 		}
-		
-		return nil, fmt.Errorf("another instance is already running")
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
 	}
-
-	// Write current PID to lock file
-	lockFile.Truncate(0)
-	lockFile.Seek(0, 0)
-	fmt.Fprintf(lockFile, "%d\n", os.Getpid())
-	lockFile.Sync()
-
 	return lockFile, nil
 }
 
+func processAlive(pid int) bool {
+	// os.FindProcess opens a handle on Windows and fails for exited processes.
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
 func releaseLock(lockFile *os.File) {
 	if lockFile != nil {
-		var overlapped syscall.Overlapped
+		overlapped := syscall.Overlapped{Offset: lockByteOffset}
 		handle := syscall.Handle(lockFile.Fd())
 		procUnlockFileEx.Call(
 			uintptr(handle),
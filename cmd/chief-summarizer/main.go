@@ -8,19 +8,14 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/blang/semver"
-	"github.com/rhysd/go-github-selfupdate/selfupdate"
-	"gopkg.in/yaml.v3"
 )
 
 const version = "1.1.0"
@@ -44,16 +39,53 @@ type Config struct {
 	Verbose           bool
 	Quiet             bool
 	Excludes          []*regexp.Regexp
+	Includes          []*regexp.Regexp
+	UseGitignore      bool
 	RequestTimeout    time.Duration
 	ConfigPath        string
 	DisableAutoUpdate bool
+	MetricsFile       string
+	Order             string
+	Seed              int64
+	StatePath         string
+	MaxFailures       int
+	RetryBackoff      time.Duration
+	DisableValidation bool
+	ValidationRetries int
+	MinSummaryLength  int
+	MaxSummaryLength  int
+	FactCheck         string
+	Strategy          string
+	StuffMaxChars     int
+	LockName          string
+	LockWait          time.Duration
+	Rollup            bool
+	Digest            string
+	DigestDir         string
+	Entities          bool
+	Actions           bool
+	Tags              bool
+	Mood              bool
+	TagVocabulary     []string
+	ApplyTags         bool
+	Hosts             []OllamaHost
+	HostCooldown      time.Duration
+	EmbedModel        string
+	Index             bool
+	IndexFile         string
+	OnThisDayFile     string
+	Updates           updateSettings
+	UpdateStatePath   string
 }
 
 // ConfigFile represents the YAML configuration file structure.
 type ConfigFile struct {
 	Ollama struct {
-		Host            string   `yaml:"host"`
-		PreferredModels []string `yaml:"preferred_models"`
+		Host            string       `yaml:"host"`
+		Hosts           []OllamaHost `yaml:"hosts"`
+		HostCooldown    string       `yaml:"host_cooldown"`
+		PreferredModels []string     `yaml:"preferred_models"`
+		EmbedModel      string       `yaml:"embed_model"`
 	} `yaml:"ollama"`
 	Processing struct {
 		RootPath       string `yaml:"root_path"`
@@ -61,18 +93,64 @@ type ConfigFile struct {
 		ChunkOverlap   int    `yaml:"chunk_overlap"`
 		RequestTimeout string `yaml:"request_timeout"`
 		MaxFiles       int    `yaml:"max_files"`
+		Order          string `yaml:"order"`
+		Seed           int64  `yaml:"seed"`
+		Strategy       string `yaml:"strategy"`
+		StuffMaxChars  int    `yaml:"stuff_max_chars"`
+		LockName       string `yaml:"lock_name"`
+		Wait           string `yaml:"wait"`
+		Rollup         bool   `yaml:"rollup"`
 	} `yaml:"processing"`
 	Output struct {
-		ForceOverwrite bool `yaml:"force_overwrite"`
-		Verbose        bool `yaml:"verbose"`
-		Quiet          bool `yaml:"quiet"`
+		ForceOverwrite bool   `yaml:"force_overwrite"`
+		Verbose        bool   `yaml:"verbose"`
+		Quiet          bool   `yaml:"quiet"`
+		MetricsFile    string `yaml:"metrics_file"`
 	} `yaml:"output"`
 	Filters struct {
 		ExcludePatterns []string `yaml:"exclude_patterns"`
+		IncludePatterns []string `yaml:"include_patterns"`
+		UseGitignore    bool     `yaml:"use_gitignore"`
 	} `yaml:"filters"`
+	Extraction struct {
+		Entities      bool     `yaml:"entities"`
+		Actions       bool     `yaml:"actions"`
+		Tags          bool     `yaml:"tags"`
+		Mood          bool     `yaml:"mood"`
+		TagVocabulary []string `yaml:"tag_vocabulary"`
+	} `yaml:"extraction"`
 	Updates struct {
-		DisableAutoUpdate bool `yaml:"disable_autoupdate"`
+		DisableAutoUpdate bool   `yaml:"disable_autoupdate"`
+		Channel           string `yaml:"channel"`
+		CheckInterval     string `yaml:"check_interval"`
+		PublicKey         string `yaml:"public_key"`
 	} `yaml:"updates"`
+	Digests struct {
+		Periods []string `yaml:"periods"`
+		Dir     string   `yaml:"dir"`
+	} `yaml:"digests"`
+	Index struct {
+		Enabled bool   `yaml:"enabled"`
+		Path    string `yaml:"path"`
+	} `yaml:"index"`
+	OnThisDay struct {
+		Output string `yaml:"output"`
+	} `yaml:"onthisday"`
+	State struct {
+		Path         string `yaml:"path"`
+		MaxFailures  int    `yaml:"max_failures"`
+		RetryBackoff string `yaml:"retry_backoff"`
+	} `yaml:"state"`
+	Validation struct {
+		Disable   bool   `yaml:"disable"`
+		Retries   *int   `yaml:"retries"`
+		MinLength int    `yaml:"min_length"`
+		MaxLength int    `yaml:"max_length"`
+		FactCheck string `yaml:"fact_check"`
+	} `yaml:"validation"`
+	// Profiles are named sets of settings layered over the ones above with
+	// -profile NAME.
+	Profiles map[string]ConfigFile `yaml:"profiles"`
 }
 
 type multiFlag []string
@@ -87,74 +165,60 @@ func (m *multiFlag) Set(value string) error {
 }
 
 func main() {
-	cfg := parseFlags()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "clean":
+			os.Exit(runClean(os.Args[2:]))
+		case "self-update":
+			os.Exit(runSelfUpdate(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "onthisday":
+			os.Exit(runOnThisDay(os.Args[2:]))
+		case "ask":
+			os.Exit(runAsk(os.Args[2:]))
+		}
+	}
 
-	// Acquire lock to prevent concurrent runs
-	lockFile, err := acquireLock()
+	cfg := parseFlags(flag.CommandLine, os.Args[1:], "chief-summarizer [flags] <root-path>")
+
+	// Acquire lock to prevent concurrent runs on the same root
+	lockFile, err := acquireLock(cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERR  %v\n", err)
-		os.Exit(1)
+		failRun(cfg, "ERR  %v\n", err)
 	}
 	defer releaseLock(lockFile)
 
 	// Check for updates (unless disabled)
 	if !cfg.DisableAutoUpdate {
-		doSelfUpdate()
+		autoUpdate(cfg)
 	}
 
 	httpClient.Timeout = cfg.RequestTimeout
 
-	model, err := chooseModel(cfg)
+	ollamaPool = newHostPool(cfg.Hosts, cfg.HostCooldown, cfg)
+	model, err := ollamaPool.checkAll()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERR  model selection failed: %v\n", err)
-		os.Exit(1)
+		failRun(cfg, "ERR  model selection failed: %v\n", err)
 	}
 	cfg.Model = model
+	runMetrics.Model = model
 
 	if !cfg.Quiet {
 		fmt.Printf("Using model: %s\n", cfg.Model)
 	}
 
-	hadError := false
-	plans := make([]string, 0)
-
-	err = filepath.WalkDir(cfg.RootDir, func(path string, d fs.DirEntry, walkErr error) error {
-		if walkErr != nil {
-			errorf("ERR  %s (walk error: %v)\n", path, walkErr)
-			hadError = true
-			return nil
-		}
-		display := displayPath(path, cfg.RootDir)
-		if matchesExclude(path, cfg.RootDir, cfg.Excludes) {
-			if d.IsDir() {
-				if cfg.Verbose {
-					statusf(cfg, "SKIP %s (directory excluded)\n", display)
-				}
-				return filepath.SkipDir
-			}
-			if cfg.Verbose {
-				statusf(cfg, "SKIP %s (excluded by pattern)\n", display)
-			}
-			return nil
-		}
-		if d.IsDir() || !isMarkdown(path) || isSummaryFile(path) {
-			return nil
-		}
-
-		plans = append(plans, path)
-		return nil
-	})
-
+	state, err := openState(cfg.StatePath)
 	if err != nil {
-		errorf("ERR  walk error: %v\n", err)
-		hadError = true
+		errorf("WARN state file %s unreadable, starting fresh: %v\n", cfg.StatePath, err)
 	}
 
+	plans, hadError := discoverFiles(cfg)
+
 	processed := 0
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
-	rnd.Shuffle(len(plans), func(i, j int) {
-		plans[i], plans[j] = plans[j], plans[i]
-	})
+	orderPlans(plans, cfg.Order, cfg.Seed)
 
 	for _, path := range plans {
 		if cfg.MaxFiles > 0 && processed >= cfg.MaxFiles {
@@ -166,10 +230,33 @@ func main() {
 
 		if !cfg.Force {
 			if _, err := os.Stat(summaryPath); err == nil {
-				if cfg.Verbose {
-					statusf(cfg, "SKIP %s (summary exists)\n", display)
+				if summaryComplete(summaryPath) {
+					if cfg.Verbose {
+						statusf(cfg, "SKIP %s (summary exists)\n", display)
+					}
+					runMetrics.Skipped++
+					continue
+				}
+				if !summaryGenerated(path, summaryPath, state) {
+					statusf(cfg, "WARN %s (summary has no generator footer, keeping it; use -force to overwrite)\n", display)
+					runMetrics.Skipped++
+					continue
+				}
+				statusf(cfg, "WARN %s (summary is truncated or invalid, regenerating)\n", display)
+			}
+		}
+
+		var hash string
+		if !cfg.Force {
+			if h, err := hashFile(path); err == nil {
+				hash = h
+				if skip, reason := retryDecision(state.Get(path), hash, cfg.MaxFailures, cfg.RetryBackoff, time.Now()); skip {
+					if cfg.Verbose {
+						statusf(cfg, "SKIP %s (%s)\n", display, reason)
+					}
+					runMetrics.Skipped++
+					continue
 				}
-				continue
 			}
 		}
 
@@ -183,125 +270,426 @@ func main() {
 			continue
 		}
 
-		if err := processFile(path, summaryPath, cfg); err != nil {
+		attemptStart := time.Now()
+		err := processFile(path, summaryPath, cfg)
+		if hash == "" {
+			hash, _ = hashFile(path)
+		}
+		if !errors.Is(err, ErrEmptyFile) {
+			state.RecordAttempt(path, hash, cfg.Model, attemptStart, time.Since(attemptStart), err)
+			if saveErr := state.Save(); saveErr != nil {
+				errorf("WARN failed to save state: %v\n", saveErr)
+			}
+		}
+		if err != nil {
 			if errors.Is(err, ErrEmptyFile) {
 				if cfg.Verbose {
 					statusf(cfg, "WARN %s (file is empty)\n", display)
 				}
+				runMetrics.Empty++
 			} else {
 				errorf("ERR  %s (%v)\n", display, err)
 				hadError = true
+				runMetrics.Failed++
 			}
 		} else {
 			statusf(cfg, "OK   %s -> %s\n", display, summaryDisplay)
+			runMetrics.Processed++
 		}
 		processed++
 	}
 
+	if cfg.Rollup && rollupFolders(plans, cfg) {
+		hadError = true
+	}
+	if cfg.Digest != "" && writeDigests(plans, cfg) {
+		hadError = true
+	}
+	if cfg.Entities && writeEntitiesReport(plans, cfg) {
+		hadError = true
+	}
+	if cfg.Actions && writeOpenActionsReport(plans, cfg) {
+		hadError = true
+	}
+	if cfg.Mood && writeMoodTimelines(plans, cfg) {
+		hadError = true
+	}
+	if cfg.Index && updateEmbeddingIndex(plans, cfg) {
+		hadError = true
+	}
+
+	if cfg.MetricsFile != "" && !cfg.DryRun {
+		for _, path := range plans {
+			if _, err := os.Stat(summaryFilename(path)); err != nil {
+				runMetrics.Backlog++
+			}
+		}
+		if err := writeMetricsFile(cfg.MetricsFile, runMetrics, !hadError); err != nil {
+			errorf("ERR  write metrics file: %v\n", err)
+			hadError = true
+		}
+	}
+
 	if hadError {
 		fmt.Fprintln(os.Stderr, "ERR  One or more errors occurred during processing.")
 		os.Exit(1)
 	}
 }
 
-func parseFlags() Config {
-	var cfg Config
-	flag.StringVar(&cfg.Host, "host", "http://localhost:11434", "Ollama host URL")
-	flag.StringVar(&cfg.Model, "model", "", "Model name (optional)")
-	flag.IntVar(&cfg.ChunkSize, "chunk-size", 4000, "Chunk size in characters")
-	flag.IntVar(&cfg.ChunkOverlap, "chunk-overlap", 400, "Chunk overlap in characters")
-	flag.BoolVar(&cfg.Force, "force", false, "Overwrite existing *_summary.md files")
-	flag.BoolVar(&cfg.DryRun, "dry-run", false, "Dry run (no LLM calls, no writes)")
-	flag.IntVar(&cfg.MaxFiles, "max-files", 0, "Max files to process (0 = unlimited)")
-	flag.BoolVar(&cfg.Verbose, "verbose", false, "Verbose output")
-	flag.BoolVar(&cfg.Quiet, "quiet", false, "Suppress progress/status output (errors still reported)")
-	flag.DurationVar(&cfg.RequestTimeout, "request-timeout", 10*time.Minute, "HTTP request timeout (e.g. 600s, 10m)")
-	flag.BoolVar(&cfg.DisableAutoUpdate, "disable-autoupdate", false, "Disable automatic update checks")
-	var excludePatterns multiFlag
-	flag.Var(&excludePatterns, "exclude", "Regular expression for paths to skip (repeatable)")
-	showVersion := flag.Bool("version", false, "Print version and exit")
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: chief-summarizer [flags] <root-path>\n")
-		flag.PrintDefaults()
-	}
-	flag.Parse()
-
-	if *showVersion {
-		fmt.Printf("chief-summarizer v%s\n", version)
-		os.Exit(0)
+// failRun reports an error that keeps the run from starting, records the
+// failed run in the metrics file and exits.
+func failRun(cfg Config, format string, args ...any) {
+	errorf(format, args...)
+	if cfg.MetricsFile != "" && !cfg.DryRun {
+		if err := writeMetricsFile(cfg.MetricsFile, runMetrics, false); err != nil {
+			errorf("ERR  write metrics file: %v\n", err)
+		}
 	}
+	os.Exit(1)
+}
+
+// discoverFiles walks cfg.RootDir and returns the markdown sources that are
+// candidates for summarization. The bool reports whether walk errors occurred.
+func discoverFiles(cfg Config) ([]string, bool) {
+	hadError := false
+	plans := make([]string, 0)
+	filter := newPathFilter(cfg)
+
+	err := filepath.WalkDir(cfg.RootDir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			errorf("ERR  %s (walk error: %v)\n", path, walkErr)
+			hadError = true
+			return nil
+		}
+		display := displayPath(path, cfg.RootDir)
+		if reason := filter.excluded(path, d.IsDir()); reason != "" {
+			if cfg.Verbose {
+				statusf(cfg, "SKIP %s (%s)\n", display, reason)
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isMarkdown(path) || isSummaryFile(path) || isDigestFile(path) || isReportFile(path) || isOnThisDayNote(path, cfg) {
+			return nil
+		}
+		if !filter.included(path) {
+			if cfg.Verbose {
+				statusf(cfg, "SKIP %s (not included)\n", display)
+			}
+			return nil
+		}
+
+		plans = append(plans, path)
+		return nil
+	})
 
-	// Determine config file path and load if it exists
-	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERR  determine home directory: %v\n", err)
-		os.Exit(1)
+		errorf("ERR  walk error: %v\n", err)
+		hadError = true
+	}
+	return plans, hadError
+}
+
+// cliOptions holds the values bound to the command line flags. The config
+// file is merged into cfg by applyConfigFile after parsing.
+type cliOptions struct {
+	cfg             Config
+	excludePatterns multiFlag
+	includePatterns multiFlag
+	showVersion     bool
+	configPath      string
+	profile         string
+}
+
+// sharedFlags are defined for every subcommand: where the config comes from,
+// which files are considered and how much is printed.
+var sharedFlags = []string{"config", "profile", "exclude", "include", "gitignore", "verbose", "quiet"}
+
+// registerFlags defines the options of the main command on flags. Given
+// names, it defines only those and sharedFlags, for subcommands that read
+// just a few settings; the others keep their defaults and config file values.
+func registerFlags(flags *flag.FlagSet, names ...string) *cliOptions {
+	defs := flags
+	if len(names) > 0 {
+		defs = flag.NewFlagSet(flags.Name(), flag.ContinueOnError)
+	}
+	o := &cliOptions{}
+	cfg := &o.cfg
+	defs.StringVar(&cfg.Host, "host", "http://localhost:11434", "Ollama host URL")
+	defs.StringVar(&cfg.Model, "model", "", "Model name (optional)")
+	defs.DurationVar(&cfg.HostCooldown, "host-cooldown", time.Minute, "Keep a failing Ollama host out of rotation this long")
+	defs.StringVar(&cfg.EmbedModel, "embed-model", "", "Ollama embedding model for semantic search, e.g. nomic-embed-text (empty = keyword search only)")
+	defs.IntVar(&cfg.ChunkSize, "chunk-size", 4000, "Chunk size in characters")
+	defs.IntVar(&cfg.ChunkOverlap, "chunk-overlap", 400, "Chunk overlap in characters")
+	defs.BoolVar(&cfg.Force, "force", false, "Overwrite existing *_summary.md files")
+	defs.BoolVar(&cfg.DryRun, "dry-run", false, "Dry run (no LLM calls, no writes)")
+	defs.BoolVar(&cfg.Rollup, "rollup", false, "Afterwards write a "+folderSummaryName+" overview into every folder")
+	defs.StringVar(&cfg.Digest, "digest", "", "Afterwards write digests of dated documents for these periods: "+strings.Join(digestPeriods, ", ")+" (comma-separated)")
+	defs.StringVar(&cfg.DigestDir, "digest-dir", "_digests", "Directory for digests, relative to the root path")
+	defs.BoolVar(&cfg.Index, "index", false, "Afterwards embed new summaries and source chunks with -embed-model into the embedding index")
+	defs.StringVar(&cfg.IndexFile, "index-file", ".chief-summarizer-index.json", "Embedding index file, relative to the root path")
+	defs.BoolVar(&cfg.Entities, "entities", false, "Extract people, places and organizations per document and index them in "+entitiesReportName)
+	defs.BoolVar(&cfg.Actions, "actions", false, "Extract tasks and decisions per document and list open tasks in "+openActionsReportName)
+	defs.BoolVar(&cfg.Tags, "tags", false, "Propose tags per document for the summary frontmatter and offer them as <name>_tags.patch for the source")
+	defs.BoolVar(&cfg.Mood, "mood", false, "Rate the mood of each dated diary entry (-2..+2) and write yearly timelines to _mood_YYYY.csv and _mood_YYYY.md")
+	defs.BoolVar(&cfg.ApplyTags, "apply-tags", false, "With -tags, add the proposed tags to the source frontmatter instead of writing a patch")
+	defs.IntVar(&cfg.MaxFiles, "max-files", 0, "Max files to process (0 = unlimited)")
+	defs.StringVar(&cfg.Strategy, "strategy", strategyMapReduce, "Summarization strategy: "+strings.Join(summarizationStrategies, ", "))
+	defs.IntVar(&cfg.StuffMaxChars, "stuff-max-chars", 8000, "Largest document (in characters) summarized in one call by -strategy stuff")
+	defs.StringVar(&cfg.Order, "order", orderRandom, "Processing order: "+strings.Join(processingOrders, ", "))
+	defs.Int64Var(&cfg.Seed, "seed", 0, "Seed for -order random (0 = time-based)")
+	defs.BoolVar(&cfg.Verbose, "verbose", false, "Verbose output")
+	defs.BoolVar(&cfg.Quiet, "quiet", false, "Suppress progress/status output (errors still reported)")
+	defs.DurationVar(&cfg.RequestTimeout, "request-timeout", 10*time.Minute, "HTTP request timeout (e.g. 600s, 10m)")
+	defs.BoolVar(&cfg.DisableAutoUpdate, "disable-autoupdate", false, "Disable automatic update checks")
+	defs.StringVar(&cfg.LockName, "lock-name", "", "Name of the run lock (default derived from the root path)")
+	defs.DurationVar(&cfg.LockWait, "wait", 0, "Wait this long for another run on the same root to finish (0 = fail immediately)")
+	defs.StringVar(&cfg.MetricsFile, "metrics-file", "", "Write Prometheus textfile metrics to this .prom file after the run")
+	defs.StringVar(&cfg.StatePath, "state-file", "", "Path of the run-history state file (default $XDG_STATE_HOME/chief-summarizer/state.json)")
+	defs.IntVar(&cfg.MaxFailures, "max-failures", 5, "Skip files that failed this many times in a row until they change (0 = never give up)")
+	defs.DurationVar(&cfg.RetryBackoff, "retry-backoff", time.Hour, "Initial delay before retrying a failed file; doubles per failure (0 = retry every run)")
+	defs.BoolVar(&cfg.DisableValidation, "disable-validation", false, "Accept final summaries without checking headings, length, language and prompt leakage")
+	defs.IntVar(&cfg.ValidationRetries, "validation-retries", 2, "Regenerate a rejected final summary this many times before failing the file")
+	defs.IntVar(&cfg.MinSummaryLength, "min-summary-length", 80, "Minimum summary length in characters (0 = no minimum)")
+	defs.IntVar(&cfg.MaxSummaryLength, "max-summary-length", 20000, "Maximum summary length in characters (0 = no maximum)")
+	defs.StringVar(&cfg.FactCheck, "fact-check", factCheckOff, "Check summary dates, numbers and names against the source: "+strings.Join(factCheckModes, ", "))
+	defs.Var(&o.excludePatterns, "exclude", "Regular expression for paths to skip (repeatable)")
+	defs.Var(&o.includePatterns, "include", "Only summarize files matching this glob, or regular expression prefixed with re: (repeatable)")
+	defs.BoolVar(&cfg.UseGitignore, "gitignore", false, "Also skip paths ignored by .gitignore files")
+	defs.BoolVar(&o.showVersion, "version", false, "Print version and exit")
+	defs.StringVar(&o.configPath, "config", "", "Path of the config file (default $XDG_CONFIG_HOME/chiefsummarizer.yaml)")
+	defs.StringVar(&o.profile, "profile", "", "Config profile to layer over the base settings (default $CHIEF_SUMMARIZER_PROFILE)")
+	if len(names) > 0 {
+		for _, name := range append(append([]string(nil), sharedFlags...), names...) {
+			f := defs.Lookup(name)
+			flags.Var(f.Value, f.Name, f.Usage)
+		}
 	}
-	cfg.ConfigPath = filepath.Join(homeDir, ".config", "chiefsummarizer.yaml")
+	return o
+}
 
-	var configFile *ConfigFile
-	if _, err := os.Stat(cfg.ConfigPath); err == nil {
-		// Config file exists, try to load it
-		configFile, err = loadConfigFile(cfg.ConfigPath)
+// applyConfigFile fills in the settings that were not set explicitly on the
+// command line from the config file, profile and environment. A flag given
+// on the command line always wins, even when it equals its default.
+func applyConfigFile(o *cliOptions, flags *flag.FlagSet, loaded *loadedConfig, homeDir string) error {
+	cfg := &o.cfg
+	configFile := loaded.File
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	use := func(flagName, key string) bool {
+		return !explicit[flagName] && loaded.has(key)
+	}
+	duration := func(value string, target *time.Duration) error {
+		d, err := time.ParseDuration(value)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERR  failed to load config file: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid duration %q: %w", value, err)
 		}
-	} else {
-		// Config file doesn't exist, use empty config
-		configFile = &ConfigFile{}
+		*target = d
+		return nil
 	}
 
-	// Apply config file defaults (CLI flags override config file)
-	if cfg.Host == "http://localhost:11434" && configFile.Ollama.Host != "" {
+	if use("host", "ollama.host") {
 		cfg.Host = configFile.Ollama.Host
 	}
+	if !explicit["host"] && len(configFile.Ollama.Hosts) > 0 {
+		cfg.Hosts = configFile.Ollama.Hosts
+	} else {
+		cfg.Hosts = []OllamaHost{{URL: cfg.Host}}
+	}
+	if use("host-cooldown", "ollama.host_cooldown") {
+		if err := duration(configFile.Ollama.HostCooldown, &cfg.HostCooldown); err != nil {
+			return err
+		}
+	}
+	if use("embed-model", "ollama.embed_model") {
+		cfg.EmbedModel = configFile.Ollama.EmbedModel
+	}
 	if len(configFile.Ollama.PreferredModels) > 0 {
 		preferredModels = configFile.Ollama.PreferredModels
 	}
-	if cfg.ChunkSize == 4000 && configFile.Processing.ChunkSize > 0 {
+	if use("chunk-size", "processing.chunk_size") {
 		cfg.ChunkSize = configFile.Processing.ChunkSize
 	}
-	if cfg.ChunkOverlap == 400 && configFile.Processing.ChunkOverlap > 0 {
+	if use("chunk-overlap", "processing.chunk_overlap") {
 		cfg.ChunkOverlap = configFile.Processing.ChunkOverlap
 	}
-	if cfg.RequestTimeout == 10*time.Minute && configFile.Processing.RequestTimeout != "" {
-		if timeout, err := time.ParseDuration(configFile.Processing.RequestTimeout); err == nil {
-			cfg.RequestTimeout = timeout
+	if use("request-timeout", "processing.request_timeout") {
+		if err := duration(configFile.Processing.RequestTimeout, &cfg.RequestTimeout); err != nil {
+			return err
 		}
 	}
-	if cfg.MaxFiles == 0 && configFile.Processing.MaxFiles > 0 {
+	if use("max-files", "processing.max_files") {
 		cfg.MaxFiles = configFile.Processing.MaxFiles
 	}
-	if !cfg.Force && configFile.Output.ForceOverwrite {
+	if use("strategy", "processing.strategy") {
+		cfg.Strategy = configFile.Processing.Strategy
+	}
+	if use("stuff-max-chars", "processing.stuff_max_chars") {
+		cfg.StuffMaxChars = configFile.Processing.StuffMaxChars
+	}
+	if use("order", "processing.order") {
+		cfg.Order = configFile.Processing.Order
+	}
+	if use("seed", "processing.seed") {
+		cfg.Seed = configFile.Processing.Seed
+	}
+	if use("lock-name", "processing.lock_name") {
+		cfg.LockName = configFile.Processing.LockName
+	}
+	if use("wait", "processing.wait") {
+		if err := duration(configFile.Processing.Wait, &cfg.LockWait); err != nil {
+			return err
+		}
+	}
+	if use("rollup", "processing.rollup") {
+		cfg.Rollup = configFile.Processing.Rollup
+	}
+	if use("digest", "digests.periods") {
+		cfg.Digest = strings.Join(configFile.Digests.Periods, ",")
+	}
+	if use("digest-dir", "digests.dir") {
+		cfg.DigestDir = expandHome(configFile.Digests.Dir, homeDir)
+	}
+	if use("index", "index.enabled") {
+		cfg.Index = configFile.Index.Enabled
+	}
+	if use("index-file", "index.path") {
+		cfg.IndexFile = expandHome(configFile.Index.Path, homeDir)
+	}
+	cfg.OnThisDayFile = onThisDayName
+	if loaded.has("onthisday.output") {
+		cfg.OnThisDayFile = expandHome(configFile.OnThisDay.Output, homeDir)
+	}
+	if use("entities", "extraction.entities") {
+		cfg.Entities = configFile.Extraction.Entities
+	}
+	if use("actions", "extraction.actions") {
+		cfg.Actions = configFile.Extraction.Actions
+	}
+	if use("tags", "extraction.tags") {
+		cfg.Tags = configFile.Extraction.Tags
+	}
+	cfg.TagVocabulary = configFile.Extraction.TagVocabulary
+	if use("mood", "extraction.mood") {
+		cfg.Mood = configFile.Extraction.Mood
+	}
+	if use("force", "output.force_overwrite") {
 		cfg.Force = configFile.Output.ForceOverwrite
 	}
-	if !cfg.Verbose && configFile.Output.Verbose {
+	if use("verbose", "output.verbose") {
 		cfg.Verbose = configFile.Output.Verbose
 	}
-	if !cfg.Quiet && configFile.Output.Quiet {
+	if use("quiet", "output.quiet") {
 		cfg.Quiet = configFile.Output.Quiet
 	}
-	if !cfg.DisableAutoUpdate && configFile.Updates.DisableAutoUpdate {
+	if use("metrics-file", "output.metrics_file") {
+		cfg.MetricsFile = expandHome(configFile.Output.MetricsFile, homeDir)
+	}
+	if use("disable-autoupdate", "updates.disable_autoupdate") {
 		cfg.DisableAutoUpdate = configFile.Updates.DisableAutoUpdate
 	}
-	if len(excludePatterns) == 0 && len(configFile.Filters.ExcludePatterns) > 0 {
-		excludePatterns = configFile.Filters.ExcludePatterns
+	updates, err := loadUpdateSettings(configFile)
+	if err != nil {
+		return err
+	}
+	cfg.Updates = updates
+	cfg.UpdateStatePath = updateStatePath(homeDir)
+	if use("state-file", "state.path") {
+		cfg.StatePath = expandHome(configFile.State.Path, homeDir)
+	}
+	if cfg.StatePath == "" {
+		cfg.StatePath = defaultStatePath(homeDir)
+	}
+	if use("max-failures", "state.max_failures") {
+		cfg.MaxFailures = configFile.State.MaxFailures
+	}
+	if use("retry-backoff", "state.retry_backoff") {
+		if err := duration(configFile.State.RetryBackoff, &cfg.RetryBackoff); err != nil {
+			return err
+		}
+	}
+	if use("disable-validation", "validation.disable") {
+		cfg.DisableValidation = configFile.Validation.Disable
+	}
+	if use("validation-retries", "validation.retries") && configFile.Validation.Retries != nil {
+		cfg.ValidationRetries = *configFile.Validation.Retries
+	}
+	if use("min-summary-length", "validation.min_length") {
+		cfg.MinSummaryLength = configFile.Validation.MinLength
+	}
+	if use("max-summary-length", "validation.max_length") {
+		cfg.MaxSummaryLength = configFile.Validation.MaxLength
+	}
+	if use("fact-check", "validation.fact_check") {
+		cfg.FactCheck = configFile.Validation.FactCheck
+	}
+	if use("exclude", "filters.exclude_patterns") {
+		o.excludePatterns = configFile.Filters.ExcludePatterns
+	}
+	if use("include", "filters.include_patterns") {
+		o.includePatterns = configFile.Filters.IncludePatterns
+	}
+	if use("gitignore", "filters.use_gitignore") {
+		cfg.UseGitignore = configFile.Filters.UseGitignore
+	}
+	return nil
+}
+
+// parseFlags registers the options on flags (all of them, or names and
+// sharedFlags; see registerFlags), parses args and merges in the config file.
+// usage is the synopsis printed by -h.
+func parseFlags(flags *flag.FlagSet, args []string, usage string, names ...string) Config {
+	o := registerFlags(flags, names...)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s\n", usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if o.showVersion {
+		fmt.Printf("chief-summarizer v%s\n", version)
+		os.Exit(0)
 	}
 
+	// Determine config file path and load if it exists
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERR  determine home directory: %v\n", err)
+		os.Exit(1)
+	}
+	loaded, err := loadConfig(configPathFor(o.configPath, homeDir), profileFor(o.profile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERR  failed to load config file: %v\n", err)
+		os.Exit(1)
+	}
+	for _, key := range loaded.Unknown {
+		fmt.Fprintf(os.Stderr, "WARN %s: unknown key %s\n", loaded.Path, key)
+	}
+	if problems := validateConfigFile(loaded.File, loaded.has); len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "ERR  %s: %s\n", loaded.Path, problem)
+		}
+		os.Exit(2)
+	}
+	if err := applyConfigFile(o, flags, loaded, homeDir); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR  %v\n", err)
+		os.Exit(2)
+	}
+	cfg := o.cfg
+	cfg.ConfigPath = loaded.Path
+	configFile := loaded.File
+	excludePatterns := o.excludePatterns
+
 	// Determine root directory (CLI arg or config file)
-	if flag.NArg() > 0 {
-		cfg.RootDir = flag.Arg(0)
+	if flags.NArg() > 0 {
+		cfg.RootDir = flags.Arg(0)
 	} else if configFile.Processing.RootPath != "" {
-		// Expand ~ in root path
-		if strings.HasPrefix(configFile.Processing.RootPath, "~/") {
-			cfg.RootDir = filepath.Join(homeDir, configFile.Processing.RootPath[2:])
-		} else {
-			cfg.RootDir = configFile.Processing.RootPath
-		}
+		cfg.RootDir = expandHome(configFile.Processing.RootPath, homeDir)
 	} else {
 		fmt.Fprintln(os.Stderr, "ERR  root path must be specified via command line argument or config file (processing.root_path)")
-		flag.Usage()
+		flags.Usage()
 		os.Exit(2)
 	}
 
@@ -320,39 +708,66 @@ func parseFlags() Config {
 			cfg.Excludes = append(cfg.Excludes, re)
 		}
 	}
+	for _, pattern := range o.includePatterns {
+		re, err := compileInclude(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERR  invalid -include pattern %q: %v\n", pattern, err)
+			os.Exit(2)
+		}
+		cfg.Includes = append(cfg.Includes, re)
+	}
 	if cfg.RequestTimeout <= 0 {
 		cfg.RequestTimeout = 10 * time.Minute
 	}
+	for _, host := range cfg.Hosts {
+		if host.URL == "" {
+			fmt.Fprintln(os.Stderr, "ERR  every entry in ollama.hosts needs a url")
+			os.Exit(2)
+		}
+	}
+	if cfg.ValidationRetries < 0 {
+		cfg.ValidationRetries = 0
+	}
+	// Check only the options this command defines; subcommands ignore the rest.
+	defined := func(name string) bool { return flags.Lookup(name) != nil }
+	if defined("strategy") && !containsString(summarizationStrategies, cfg.Strategy) {
+		fmt.Fprintf(os.Stderr, "ERR  invalid -strategy %q (expected one of: %s)\n", cfg.Strategy, strings.Join(summarizationStrategies, ", "))
+		os.Exit(2)
+	}
+	if defined("fact-check") && !containsString(factCheckModes, cfg.FactCheck) {
+		fmt.Fprintf(os.Stderr, "ERR  invalid -fact-check %q (expected one of: %s)\n", cfg.FactCheck, strings.Join(factCheckModes, ", "))
+		os.Exit(2)
+	}
+	if err := validateOrder(cfg.Order); defined("order") && err != nil {
+		fmt.Fprintf(os.Stderr, "ERR  invalid -order: %v\n", err)
+		os.Exit(2)
+	}
+	if _, err := parseDigestPeriods(cfg.Digest); defined("digest") && err != nil {
+		fmt.Fprintf(os.Stderr, "ERR  invalid -digest: %v\n", err)
+		os.Exit(2)
+	}
+	if defined("index") && cfg.Index && cfg.EmbedModel == "" {
+		fmt.Fprintln(os.Stderr, "ERR  -index needs an embedding model (-embed-model or ollama.embed_model)")
+		os.Exit(2)
+	}
 
 	return cfg
 }
 
-func loadConfigFile(path string) (*ConfigFile, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// expandHome replaces a leading "~/" in path with the user's home directory.
+func expandHome(path, homeDir string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, path[2:])
 	}
-	var cfg ConfigFile
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
-	}
-	return &cfg, nil
+	return path
 }
 
-func chooseModel(cfg Config) (string, error) {
+// chooseModel resolves the model to use on host from the models it has
+// installed (nil if they couldn't be listed).
+func chooseModel(cfg Config, host string, available []string) (string, error) {
 	if cfg.Model != "" {
 		return cfg.Model, nil
 	}
-	available, err := listAvailableModels(cfg.Host)
-	if err != nil {
-		if cfg.Verbose {
-			fmt.Fprintf(os.Stderr, "WARN unable to query models from %s: %v\n", cfg.Host, err)
-		}
-		if len(preferredModels) == 0 {
-			return "", errors.New("no preferred models configured")
-		}
-		return preferredModels[0], nil
-	}
 	if len(available) == 0 {
 		if len(preferredModels) == 0 {
 			return "", errors.New("no preferred models configured")
@@ -369,13 +784,13 @@ func chooseModel(cfg Config) (string, error) {
 		}
 		if match, ok := findClosestModel(preferred, available); ok {
 			if cfg.Verbose {
-				fmt.Fprintf(os.Stderr, "INFO using closest installed model %s for preferred %s\n", match, preferred)
+				fmt.Fprintf(os.Stderr, "INFO using closest installed model %s on %s for preferred %s\n", match, host, preferred)
 			}
 			return match, nil
 		}
 	}
 	fallback := available[0]
-	fmt.Fprintf(os.Stderr, "WARN none of the preferred models %v are installed; using %s instead\n", preferredModels, fallback)
+	fmt.Fprintf(os.Stderr, "WARN none of the preferred models %v are installed on %s; using %s instead\n", preferredModels, host, fallback)
 	return fallback, nil
 }
 
@@ -395,68 +810,128 @@ func processFile(path, summaryPath string, cfg Config) error {
 		chunks = []string{trimmed}
 	}
 
-	chunksPath := chunksFilename(path)
-	var chunkSummaries []string
+	runeCount := len([]rune(trimmed))
+	lengthCategory := lengthCategoryFromRunes(runeCount)
+	sourceLang := detectLanguage(trimmed)
+	display := displayPath(path, cfg.RootDir)
 
-	if !cfg.Force {
-		if savedData, err := os.ReadFile(chunksPath); err == nil {
-			var saved []string
-			if err := json.Unmarshal(savedData, &saved); err == nil {
-				if len(saved) <= len(chunks) {
-					chunkSummaries = saved
-					if len(chunkSummaries) > 0 {
-						statusf(cfg, "RESUME %s (loaded %d/%d chunks)\n", displayPath(path, cfg.RootDir), len(chunkSummaries), len(chunks))
-					}
-				}
-			}
+	strategy := cfg.Strategy
+	if strategy == strategyStuff && runeCount > cfg.StuffMaxChars {
+		if cfg.Verbose {
+			statusf(cfg, "INFO %s (%d characters exceed -stuff-max-chars, using map-reduce)\n", display, runeCount)
+		}
+		strategy = strategyMapReduce
+	}
+
+	var finalSummary string
+	chunkCount := len(chunks)
+	var ckpt *checkpointer
+	if strategy != strategyStuff {
+		ckpt = openCheckpointer(path, strategy, hashBytes(data), len(chunks), cfg)
+	}
+	switch strategy {
+	case strategyStuff:
+		chunkCount = 1
+		statusf(cfg, "FINAL %s (whole document)\n", display)
+		finalSummary, err = finalizeSummary(path, buildStuffPrompt(trimmed, lengthCategory), sourceLang, cfg)
+	case strategyRefine:
+		finalSummary, err = refineChunks(path, chunks, ckpt, lengthCategory, sourceLang, cfg)
+	default:
+		var chunkSummaries []string
+		chunkSummaries, err = summarizeChunks(path, chunks, ckpt, cfg)
+		if err == nil {
+			finalSummary, err = mergeChunkSummaries(path, chunkSummaries, lengthCategory, sourceLang, ckpt, cfg)
 		}
 	}
-
-	// Ensure capacity
-	if cap(chunkSummaries) < len(chunks) {
-		newSlice := make([]string, len(chunkSummaries), len(chunks))
-		copy(newSlice, chunkSummaries)
-		chunkSummaries = newSlice
+	if err != nil {
+		return err
 	}
+	cleanedSummary := stripThinkBlocks(finalSummary)
 
-	for idx, chunk := range chunks {
-		if idx < len(chunkSummaries) {
-			continue
-		}
-
-		statusf(cfg, "CHNK %s (%d/%d)\n", displayPath(path, cfg.RootDir), idx+1, len(chunks))
-		prompt := buildChunkPrompt(chunk)
-		resp, err := callOllama(cfg.Host, cfg.Model, prompt)
-		if err != nil {
-			return fmt.Errorf("chunk %d summarization failed: %w", idx+1, err)
+	var tags []string
+	if cfg.Tags {
+		if tags, err = proposeTags(path, cleanedSummary, cfg); err != nil {
+			statusf(cfg, "WARN %s (no tags: %v)\n", display, err)
+		} else if data, err = offerSourceTags(path, data, tags, cfg); err != nil {
+			statusf(cfg, "WARN %s (tags not offered for the source: %v)\n", display, err)
 		}
-		summary := stripThinkBlocks(resp)
-		chunkSummaries = append(chunkSummaries, summary)
+	}
 
-		if err := saveChunks(chunksPath, chunkSummaries); err != nil {
-			if cfg.Verbose {
-				statusf(cfg, "WARN failed to save checkpoint: %v\n", err)
-			}
+	var entities []Entity
+	if cfg.Entities {
+		entities = extractEntities(path, chunks, cfg)
+	}
+	// Facts are checked before the action items are appended, which quote
+	// the source rather than summarize it.
+	var facts []UnsupportedFact
+	if cfg.FactCheck != factCheckOff {
+		facts = checkFacts(cleanedSummary, trimmed)
+		if len(facts) > 0 {
+			statusf(cfg, "WARN %s (%d unsupported facts: %s)\n", displayPath(path, cfg.RootDir), len(facts), formatFacts(facts))
 		}
 	}
-	lengthCategory := lengthCategoryFromRunes(len([]rune(trimmed)))
-	finalSummary, err := mergeChunkSummaries(path, chunkSummaries, lengthCategory, cfg)
-	if err != nil {
-		return err
+	var actions []ActionItem
+	if cfg.Actions {
+		actions = extractActions(path, trimmed, chunks, cfg)
+		cleanedSummary += buildActionSection(actions, sourceLang)
 	}
-	cleanedSummary := stripThinkBlocks(finalSummary)
+	if len(facts) > 0 && cfg.FactCheck == factCheckSection {
+		cleanedSummary += buildFactWarningSection(facts)
+	}
+	var mood []MoodEntry
+	if cfg.Mood {
+		mood = scoreMood(path, trimmed, chunks, cfg)
+	}
+
 	generatedAt := time.Now()
 	duration := generatedAt.Sub(start)
-	footer := buildSummaryFooter(generatedAt, duration, len(chunkSummaries), cfg)
-	output := cleanedSummary + footer + "\n"
-	if err := os.WriteFile(summaryPath, []byte(output), 0o644); err != nil {
+	footer := buildSummaryFooter(generatedAt, duration, chunkCount, strategy, cfg)
+	output := withTagFrontmatter(cleanedSummary, tags) + footer + "\n"
+	if err := writeFileAtomic(summaryPath, []byte(output), 0o644); err != nil {
 		return fmt.Errorf("write summary: %w", err)
 	}
 
-	os.Remove(chunksPath)
+	if sidecarEnabled(cfg) {
+		meta := SummaryMeta{
+			Source:           filepath.Base(path),
+			SourceHash:       hashBytes(data),
+			GeneratedAt:      generatedAt,
+			Version:          version,
+			Model:            cfg.Model,
+			Chunks:           chunkCount,
+			Strategy:         strategy,
+			ChunkSize:        cfg.ChunkSize,
+			ChunkOverlap:     cfg.ChunkOverlap,
+			DurationSeconds:  duration.Seconds(),
+			UnsupportedFacts: facts,
+			Entities:         entities,
+			Actions:          actions,
+			Tags:             tags,
+			Mood:             mood,
+		}
+		// The summary itself is written, so a missing sidecar only costs the
+		// extras; an outdated one is removed rather than left next to it.
+		if err := writeSummaryMeta(path, meta); err != nil {
+			errorf("WARN %s (summary metadata not written: %v)\n", displayPath(path, cfg.RootDir), err)
+			os.Remove(summaryMetaFilename(path))
+		}
+	} else if err := os.Remove(summaryMetaFilename(path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errorf("WARN %s (stale summary metadata not removed: %v)\n", displayPath(path, cfg.RootDir), err)
+	}
+
+	os.Remove(chunksFilename(path))
 	return nil
 }
 
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func isMarkdown(path string) bool {
 	return filepath.Ext(path) == ".md"
 }
@@ -466,6 +941,14 @@ func isSummaryFile(path string) bool {
 	return len(base) > len("_summary.md") && base[len(base)-len("_summary.md"):] == "_summary.md"
 }
 
+// reportNames are the reports written into the root path; they are never
+// summarized themselves.
+var reportNames = []string{entitiesReportName, openActionsReportName, onThisDayName}
+
+func isReportFile(path string) bool {
+	return containsString(reportNames, filepath.Base(path)) || isMoodTimeline(path)
+}
+
 func summaryFilename(path string) string {
 	dir := filepath.Dir(path)
 	base := filepath.Base(path)
@@ -535,11 +1018,14 @@ func buildChunkPrompt(chunk string) string {
 	return b.String()
 }
 
+// finalOutputFormat describes the structure every final summary must follow.
+const finalOutputFormat = "Output format (proper Markdown with headings):\n\n1. Start with a level-2 heading: ## Ultra-Kurzfassung\n2. Below it, write two short sentences:\n   - Line 1: one short sentence describing the main topic.\n   - Line 2: one short sentence describing the main outcome or conclusion.\n\n3. Then add a blank line.\n\n4. Then add another level-2 heading: ## Ausführliche Zusammenfassung\n5. Below it, write the detailed summary:\n   - If the original document was short (~< 1.500 Wörter):\n     - write 2–4 short paragraphs OR 3–6 bullet points.\n   - If the original document was medium (1.500–5.000 Wörter):\n     - write 3–6 paragraphs and optionally 3–8 bullet points.\n   - If the original document was long (> 5.000 Wörter):\n     - use clear markdown headings (### level-3) and bullet lists for structure.\n   - Always stay focused on the key points, decisions, arguments, and results.\n\nIMPORTANT: Use proper markdown headings (## and ###) throughout. The output must be valid markdown.\n\n"
+
 func buildFinalPrompt(chunkSummaries []string, lengthCategory string) string {
 	var b strings.Builder
 	b.WriteString("You are \"Chief Summarizer\", an assistant that creates structured summaries in the original language of the source text.\n\n")
 	b.WriteString("Task:\n- You receive several partial summaries of different excerpts of ONE long markdown document.\n- The document is usually a diary written in the first person.\n- Combine them into ONE cohesive summary.\n- Remove repetition and contradictions.\n- Maintain the SAME LANGUAGE as the original text (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the source.\n- Keep important names, dates and numbers.\n- Be neutral and factual.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
-	b.WriteString("Output format (proper Markdown with headings):\n\n1. Start with a level-2 heading: ## Ultra-Kurzfassung\n2. Below it, write two short sentences:\n   - Line 1: one short sentence describing the main topic.\n   - Line 2: one short sentence describing the main outcome or conclusion.\n\n3. Then add a blank line.\n\n4. Then add another level-2 heading: ## Ausführliche Zusammenfassung\n5. Below it, write the detailed summary:\n   - If the original document was short (~< 1.500 Wörter):\n     - write 2–4 short paragraphs OR 3–6 bullet points.\n   - If the original document was medium (1.500–5.000 Wörter):\n     - write 3–6 paragraphs and optionally 3–8 bullet points.\n   - If the original document was long (> 5.000 Wörter):\n     - use clear markdown headings (### level-3) and bullet lists for structure.\n   - Always stay focused on the key points, decisions, arguments, and results.\n\nIMPORTANT: Use proper markdown headings (## and ###) throughout. The output must be valid markdown.\n\n")
+	b.WriteString(finalOutputFormat)
 	b.WriteString("Do NOT add any footer or metadata lines; the system will append them.\n\n")
 	b.WriteString(fmt.Sprintf("Original document length category: %s.\n\n", lengthCategory))
 	b.WriteString("Input:\nThe following are partial summaries of the document, in order:\n\n---\n")
@@ -562,12 +1048,37 @@ func buildIntermediatePrompt(partialSummaries []string) string {
 	return b.String()
 }
 
-func mergeChunkSummaries(path string, chunkSummaries []string, lengthCategory string, cfg Config) (string, error) {
+// mergePrompts are the prompts of one merge tree: intermediate condenses a
+// group of inputs, final turns the remaining ones into the structured summary.
+// unit and units name the original inputs in status lines.
+type mergePrompts struct {
+	intermediate func(inputs []string) string
+	final        func(inputs []string) string
+	unit, units  string
+}
+
+// mergeChunkSummaries condenses chunkSummaries hierarchically and produces the
+// final structured summary. sourceLang ("de", "en" or "") is used to validate
+// the language of the result. Finished intermediate merges are recorded in
+// ckpt, which may be nil.
+func mergeChunkSummaries(path string, chunkSummaries []string, lengthCategory, sourceLang string, ckpt *checkpointer, cfg Config) (string, error) {
 	if len(chunkSummaries) == 0 {
 		return "", errors.New("no chunk summaries to merge")
 	}
-	working := append([]string(nil), chunkSummaries...)
-	originalCount := len(chunkSummaries)
+	prompts := mergePrompts{
+		intermediate: buildIntermediatePrompt,
+		final:        func(inputs []string) string { return buildFinalPrompt(inputs, lengthCategory) },
+		unit:         "chunk",
+		units:        "chunks",
+	}
+	return mergeTree(path, chunkSummaries, prompts, sourceLang, ckpt, cfg)
+}
+
+// mergeTree merges inputs in groups of maxChunkMergeInputs, stage by stage,
+// until few enough remain for the final prompt.
+func mergeTree(path string, inputs []string, prompts mergePrompts, sourceLang string, ckpt *checkpointer, cfg Config) (string, error) {
+	working := append([]string(nil), inputs...)
+	originalCount := len(inputs)
 	stage := 0
 
 	for len(working) > maxChunkMergeInputs {
@@ -581,42 +1092,67 @@ func mergeChunkSummaries(path string, chunkSummaries []string, lengthCategory st
 			groups = append(groups, working[start:end])
 		}
 
-		condensed := make([]string, 0, len(groups))
+		condensed := make([]string, len(groups))
+		resumed := 0
+		if saved := ckpt.mergeStage(stage); len(saved) <= len(groups) {
+			resumed = copy(condensed, saved)
+		}
+		done := resumed
+		finished := make([]bool, len(groups))
 		display := displayPath(path, cfg.RootDir)
-		for idx, group := range groups {
+		var mu sync.Mutex
+		err := runParallel(len(groups)-resumed, ollamaPool.capacity(), func(i int) error {
+			idx := resumed + i
+			group := groups[idx]
 			statusf(cfg, "MERG %s (stage %d, group %d/%d, %d inputs)\n", display, stage, idx+1, len(groups), len(group))
-			prompt := buildIntermediatePrompt(group)
-			resp, err := callOllama(cfg.Host, cfg.Model, prompt)
+			prompt := prompts.intermediate(group)
+			resp, err := callOllama(prompt)
 			if err != nil {
-				return "", fmt.Errorf("intermediate merge stage %d group %d failed: %w", stage, idx+1, err)
+				return fmt.Errorf("intermediate merge stage %d group %d failed: %w", stage, idx+1, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			condensed[idx] = stripThinkBlocks(resp)
+			finished[idx] = true
+			prefix := done
+			for prefix < len(groups) && finished[prefix] {
+				prefix++
+			}
+			if prefix > done {
+				done = prefix
+				ckpt.saveMergeStage(stage, condensed[:prefix])
 			}
-			condensed = append(condensed, stripThinkBlocks(resp))
+			return nil
+		})
+		if err != nil {
+			return "", err
 		}
 		working = condensed
 	}
 
 	if originalCount == 1 {
-		statusf(cfg, "FINAL %s (formatting single chunk)\n", displayPath(path, cfg.RootDir))
+		statusf(cfg, "FINAL %s (formatting single %s)\n", displayPath(path, cfg.RootDir), prompts.unit)
 	} else {
-		statusf(cfg, "MERGE %s (final, %d inputs, %d original chunks)\n", displayPath(path, cfg.RootDir), len(working), originalCount)
+		statusf(cfg, "MERGE %s (final, %d inputs, %d original %s)\n", displayPath(path, cfg.RootDir), len(working), originalCount, prompts.units)
 	}
-	finalPrompt := buildFinalPrompt(working, lengthCategory)
-	finalSummary, err := callOllama(cfg.Host, cfg.Model, finalPrompt)
-	if err != nil {
-		return "", err
-	}
-	return stripThinkBlocks(finalSummary), nil
+	return finalizeSummary(path, prompts.final(working), sourceLang, cfg)
 }
 
-func buildSummaryFooter(generatedAt time.Time, duration time.Duration, chunkCount int, cfg Config) string {
+func buildSummaryFooter(generatedAt time.Time, duration time.Duration, chunkCount int, strategy string, cfg Config) string {
+	strategyInfo := ""
+	if strategy != strategyMapReduce {
+		strategyInfo = " | Strategy: " + strategy
+	}
 	return fmt.Sprintf(
-		"\n\n---\n_Generated automatically on %s by Chief Summarizer (AI v%s) | Model: %s | Chunks: %d | ChunkSize/Overlap: %d/%d | Duration: %s._",
+		"\n\n---\n_Generated automatically on %s by Chief Summarizer (AI v%s) | Model: %s | Chunks: %d | ChunkSize/Overlap: %d/%d%s | Duration: %s._",
 		generatedAt.Format("2006-01-02 15:04:05 MST"),
 		version,
 		cfg.Model,
 		chunkCount,
 		cfg.ChunkSize,
 		cfg.ChunkOverlap,
+		strategyInfo,
 		formatDuration(duration),
 	)
 }
@@ -699,13 +1235,57 @@ func listAvailableModels(host string) ([]string, error) {
 	return available, nil
 }
 
-func callOllama(host, model, prompt string) (string, error) {
+// callOllama sends prompt to the next host of ollamaPool.
+func callOllama(prompt string) (resp string, err error) {
+	start := time.Now()
+	defer func() {
+		runMetrics.observeLLMCall(llmGenerate, time.Since(start), err)
+	}()
+	return ollamaPool.generate(prompt, "")
+}
+
+// callOllamaJSON is callOllama with Ollama's JSON mode, for structured output.
+func callOllamaJSON(prompt string) (resp string, err error) {
+	start := time.Now()
+	defer func() {
+		runMetrics.observeLLMCall(llmJSON, time.Since(start), err)
+	}()
+	return ollamaPool.generate(prompt, "json")
+}
+
+// callOllamaEmbed computes embeddings for inputs with the embedding model.
+func callOllamaEmbed(model string, inputs []string) (vectors [][]float32, err error) {
+	start := time.Now()
+	defer func() {
+		runMetrics.observeLLMCall(llmEmbed, time.Since(start), err)
+	}()
+	return ollamaPool.embed(model, inputs)
+}
+
+// ollamaStatusError is returned for HTTP error responses from Ollama.
+type ollamaStatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *ollamaStatusError) Error() string {
+	return fmt.Sprintf("ollama request failed: %s: %s", e.Status, e.Body)
+}
+
+// generateOllama sends prompt to host. A non-empty format (e.g. "json") asks
+// Ollama to constrain the response to it.
+func generateOllama(host, model, prompt, format string) (string, error) {
 	endpoint := strings.TrimRight(host, "/") + "/api/generate"
-	body, err := json.Marshal(map[string]any{
+	request := map[string]any{
 		"model":  model,
 		"prompt": prompt,
 		"stream": false,
-	})
+	}
+	if format != "" {
+		request["format"] = format
+	}
+	body, err := json.Marshal(request)
 	if err != nil {
 		return "", err
 	}
@@ -721,7 +1301,7 @@ func callOllama(host, model, prompt string) (string, error) {
 	defer resp.Body.Close()
 	if resp.StatusCode >= 400 {
 		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
-		return "", fmt.Errorf("ollama generate failed: %s: %s", resp.Status, bytes.TrimSpace(payload))
+		return "", &ollamaStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(bytes.TrimSpace(payload))}
 	}
 	var result struct {
 		Response string `json:"response"`
@@ -735,6 +1315,42 @@ func callOllama(host, model, prompt string) (string, error) {
 	return result.Response, nil
 }
 
+// embedOllama asks host for one embedding per input.
+func embedOllama(host, model string, inputs []string) ([][]float32, error) {
+	endpoint := strings.TrimRight(host, "/") + "/api/embed"
+	body, err := json.Marshal(map[string]any{
+		"model": model,
+		"input": inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<10))
+		return nil, &ollamaStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(bytes.TrimSpace(payload))}
+	}
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(result.Embeddings), len(inputs))
+	}
+	return result.Embeddings, nil
+}
+
 func findClosestModel(preferred string, available []string) (string, bool) {
 	base := baseModelName(preferred)
 	best := ""
@@ -803,53 +1419,94 @@ func displayPath(path, root string) string {
 	return path
 }
 
-func doSelfUpdate() {
-	latest, found, err := selfupdate.DetectLatest("danst0/Chief-Summarizer")
-	if err != nil {
-		log.Println("Error occurred while detecting latest version:", err)
-		return
-	}
+func chunksFilename(path string) string {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := base[:len(base)-len(ext)]
+	return filepath.Join(dir, name+"_chunks.json")
+}
 
-	v, err := semver.Parse(version)
+// writeFileAtomic writes data to a temporary file in the target directory,
+// flushes it to disk and renames it over path, so neither readers nor a crash
+// can leave a partially written file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+tempFileMarker+"*")
 	if err != nil {
-		log.Println("Error parsing current version:", err)
-		return
+		return err
 	}
-
-	if !found || latest.Version.LTE(v) {
-		log.Printf("Current version %s is the latest\n", version)
-		return
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
 	}
-
-	log.Printf("New version %s is available! (current: %s)\n", latest.Version, version)
-	log.Println("Updating binary...")
-
-	exe, err := os.Executable()
-	if err != nil {
-		log.Println("Could not locate executable path:", err)
-		return
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
 	}
-
-	if err := selfupdate.UpdateTo(latest.AssetURL, exe); err != nil {
-		log.Println("Error occurred while updating binary:", err)
-		return
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
 	}
-
-	log.Printf("Successfully updated to version %s\n", latest.Version)
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	// Persist the rename itself; not supported on every platform.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
 }
 
-func chunksFilename(path string) string {
-	dir := filepath.Dir(path)
+// tempFileMarker is part of every temporary file name used by writeFileAtomic.
+const tempFileMarker = ".tmp-"
+
+// isTempFile reports whether path is a leftover temporary file from an
+// interrupted writeFileAtomic.
+func isTempFile(path string) bool {
 	base := filepath.Base(path)
-	ext := filepath.Ext(base)
-	name := base[:len(base)-len(ext)]
-	return filepath.Join(dir, name+"_chunks.json")
+	return strings.HasPrefix(base, ".") && strings.Contains(base, tempFileMarker)
 }
 
-func saveChunks(path string, chunks []string) error {
-	data, err := json.MarshalIndent(chunks, "", "  ")
+// summaryGenerated reports whether an incomplete summary was written by this
+// tool and may be replaced: it has (part of) the footer, a sidecar, or the run
+// history records a successful run for path. Anything else is treated as a
+// hand-written summary.
+func summaryGenerated(path, summaryPath string, state *StateDB) bool {
+	data, err := os.ReadFile(summaryPath)
+	if err == nil && strings.Contains(string(data), "_Generated automatically on ") {
+		return true
+	}
+	if _, err := os.Stat(summaryMetaFilename(path)); err == nil {
+		return true
+	}
+	entry := state.Get(path)
+	return entry != nil && !entry.LastSuccess.IsZero()
+}
+
+// summaryComplete reports whether the summary at path was fully written: it
+// must have content followed by the footer appended by processFile. A
+// truncated write loses the footer first.
+func summaryComplete(path string) bool {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return false
+	}
+	content := strings.TrimSpace(string(data))
+	loc := footerPattern.FindStringIndex(content)
+	if loc == nil || loc[0] == 0 {
+		return false
+	}
+	if strings.TrimSpace(strings.TrimSuffix(content[:loc[0]], "---")) == "" {
+		return false
 	}
-	return os.WriteFile(path, data, 0644)
+	return strings.HasSuffix(content, "._")
 }
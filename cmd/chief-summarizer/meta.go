@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"time"
+)
+
+// SummaryMeta is the machine-readable sidecar written next to a summary as
+// <name>_summary.json.
+type SummaryMeta struct {
+	Source           string            `json:"source"`
+	SourceHash       string            `json:"source_hash,omitempty"`
+	GeneratedAt      time.Time         `json:"generated_at"`
+	Version          string            `json:"version"`
+	Model            string            `json:"model"`
+	Chunks           int               `json:"chunks"`
+	Strategy         string            `json:"strategy"`
+	ChunkSize        int               `json:"chunk_size"`
+	ChunkOverlap     int               `json:"chunk_overlap"`
+	DurationSeconds  float64           `json:"duration_seconds"`
+	UnsupportedFacts []UnsupportedFact `json:"unsupported_facts,omitempty"`
+	Entities         []Entity          `json:"entities,omitempty"`
+	Actions          []ActionItem      `json:"actions,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+	Mood             []MoodEntry       `json:"mood,omitempty"`
+}
+
+func summaryMetaFilename(path string) string {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := base[:len(base)-len(ext)]
+	return filepath.Join(dir, name+"_summary.json")
+}
+
+// sidecarEnabled reports whether any enabled feature stores data in the
+// summary sidecar.
+func sidecarEnabled(cfg Config) bool {
+	return cfg.FactCheck != factCheckOff || cfg.Entities || cfg.Actions || cfg.Tags || cfg.Mood
+}
+
+func writeSummaryMeta(path string, meta SummaryMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(summaryMetaFilename(path), append(data, '\n'), 0o644)
+}
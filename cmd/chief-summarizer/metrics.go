@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// llmLatencyBuckets are the upper bounds (in seconds) of the LLM latency histogram.
+var llmLatencyBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+// runMetrics collects counters for the current run; written out by writeMetricsFile.
+var runMetrics = newRunMetrics()
+
+// RunMetrics tracks per-run statistics for the Prometheus textfile collector.
+type RunMetrics struct {
+	mu sync.Mutex
+
+	Start     time.Time
+	Model     string
+	Processed int
+	Skipped   int
+	Failed    int
+	Empty     int
+	Backlog   int
+
+	llmCalls    map[[2]string]int // by request kind and outcome
+	llmBuckets  []int
+	llmCount    int
+	llmDuration float64
+}
+
+func newRunMetrics() *RunMetrics {
+	return &RunMetrics{
+		Start:      time.Now(),
+		llmCalls:   make(map[[2]string]int),
+		llmBuckets: make([]int, len(llmLatencyBuckets)),
+	}
+}
+
+// Kinds of Ollama requests, as labelled in the metrics.
+const (
+	llmGenerate = "generate"
+	llmJSON     = "json"
+	llmEmbed    = "embed"
+)
+
+// observeLLMCall records the outcome and latency of a single Ollama request
+// of the given kind.
+func (m *RunMetrics) observeLLMCall(kind string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.llmCalls[[2]string{kind, outcome}]++
+	seconds := d.Seconds()
+	m.llmCount++
+	m.llmDuration += seconds
+	for i, bound := range llmLatencyBuckets {
+		if seconds <= bound {
+			m.llmBuckets[i]++
+		}
+	}
+}
+
+// render formats the collected metrics in the Prometheus text exposition format.
+func (m *RunMetrics) render(end time.Time, success bool) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP chief_summarizer_last_run_files Files handled in the last run by result.\n")
+	b.WriteString("# TYPE chief_summarizer_last_run_files gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_last_run_files{result=\"ok\"} %d\n", m.Processed)
+	fmt.Fprintf(&b, "chief_summarizer_last_run_files{result=\"skipped\"} %d\n", m.Skipped)
+	fmt.Fprintf(&b, "chief_summarizer_last_run_files{result=\"failed\"} %d\n", m.Failed)
+	fmt.Fprintf(&b, "chief_summarizer_last_run_files{result=\"empty\"} %d\n", m.Empty)
+
+	b.WriteString("# HELP chief_summarizer_backlog_files Markdown files still without a summary after the last run.\n")
+	b.WriteString("# TYPE chief_summarizer_backlog_files gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_backlog_files %d\n", m.Backlog)
+
+	b.WriteString("# HELP chief_summarizer_run_duration_seconds Wall-clock duration of the last run.\n")
+	b.WriteString("# TYPE chief_summarizer_run_duration_seconds gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_run_duration_seconds %s\n", formatFloat(end.Sub(m.Start).Seconds()))
+
+	b.WriteString("# HELP chief_summarizer_last_run_timestamp_seconds Unix time the last run finished.\n")
+	b.WriteString("# TYPE chief_summarizer_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_last_run_timestamp_seconds %d\n", end.Unix())
+
+	b.WriteString("# HELP chief_summarizer_last_run_success Whether the last run finished without errors; 0 also when it could not start.\n")
+	b.WriteString("# TYPE chief_summarizer_last_run_success gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_last_run_success %d\n", boolToInt(success))
+
+	b.WriteString("# HELP chief_summarizer_last_run_llm_requests Ollama requests in the last run by kind (generate, json extraction, embed) and outcome.\n")
+	b.WriteString("# TYPE chief_summarizer_last_run_llm_requests gauge\n")
+	for _, kind := range []string{llmGenerate, llmJSON, llmEmbed} {
+		for _, outcome := range []string{"success", "error"} {
+			fmt.Fprintf(&b, "chief_summarizer_last_run_llm_requests{kind=%q,outcome=%q} %d\n", kind, outcome, m.llmCalls[[2]string{kind, outcome}])
+		}
+	}
+
+	b.WriteString("# HELP chief_summarizer_llm_request_duration_seconds Latency of all Ollama requests in the last run.\n")
+	b.WriteString("# TYPE chief_summarizer_llm_request_duration_seconds histogram\n")
+	for i, bound := range llmLatencyBuckets {
+		fmt.Fprintf(&b, "chief_summarizer_llm_request_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(bound), m.llmBuckets[i])
+	}
+	fmt.Fprintf(&b, "chief_summarizer_llm_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.llmCount)
+	fmt.Fprintf(&b, "chief_summarizer_llm_request_duration_seconds_sum %s\n", formatFloat(m.llmDuration))
+	fmt.Fprintf(&b, "chief_summarizer_llm_request_duration_seconds_count %d\n", m.llmCount)
+
+	b.WriteString("# HELP chief_summarizer_model_info Model and tool version used by the last run.\n")
+	b.WriteString("# TYPE chief_summarizer_model_info gauge\n")
+	fmt.Fprintf(&b, "chief_summarizer_model_info{model=%q,version=%q} 1\n", m.Model, version)
+	return b.String()
+}
+
+// writeMetricsFile writes the metrics to path via a temporary file and rename,
+// so node_exporter never reads a partially written file.
+func writeMetricsFile(path string, m *RunMetrics, success bool) error {
+	return writeFileAtomic(path, []byte(m.render(time.Now(), success)), 0o644)
+}
+
+func formatFloat(v float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.6f", v), "0"), ".")
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
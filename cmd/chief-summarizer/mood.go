@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mood scores range from minMood (very bad) to maxMood (very good).
+const (
+	minMood = -2
+	maxMood = 2
+)
+
+// moodTimelinePattern matches the yearly timelines written into the root path
+// by -mood, e.g. _mood_2024.md and _mood_2024.csv.
+var moodTimelinePattern = regexp.MustCompile(`^_mood_\d{4}\.(?:md|csv)$`)
+
+// MoodEntry is the mood of one dated diary entry, or of one chunk when a
+// document has no dated entries. Date is empty when the entry has none.
+type MoodEntry struct {
+	Date  string `json:"date,omitempty"`
+	Chunk int    `json:"chunk,omitempty"`
+	Score int    `json:"score"`
+	Label string `json:"label"`
+}
+
+func isMoodTimeline(path string) bool {
+	return moodTimelinePattern.MatchString(filepath.Base(path))
+}
+
+func buildMoodPrompt(entry string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that rates the mood of diary entries.\n\n")
+	fmt.Fprintf(&b, "Task:\n- Read the diary entry below, written in the first person.\n- Rate the writer's overall mood on a scale from %d (very bad) to %d (very good); 0 is neutral or mixed.\n", minMood, maxMood)
+	b.WriteString("- Give a label of one to three words in the language of the entry (e.g. \"erschöpft\", \"zufrieden\", \"voller Vorfreude\").\n- Judge only what the entry says; do not guess.\n\n")
+	b.WriteString("Respond with JSON only, in exactly this form:\n{\"score\": 1, \"label\": \"zufrieden\"}\n\nEntry:\n---\n")
+	b.WriteString(entry)
+	b.WriteString("\n---\n")
+	return b.String()
+}
+
+// moodPart is a piece of a document that gets its own mood score.
+type moodPart struct {
+	date  string
+	chunk int
+	text  string
+}
+
+// moodParts splits a diary document into its dated entries, one per heading
+// with a date. Without such headings every chunk is a part, dated by the file
+// name or frontmatter if possible.
+func moodParts(path, content string, chunks []string, cfg Config) []moodPart {
+	var parts []moodPart
+	if sections := datedSections(content); len(sections) > 1 {
+		for _, section := range sections {
+			// Chunks are only numbered when an entry needs more than one.
+			entryChunks := chunkText(section.text, cfg.ChunkSize, cfg.ChunkOverlap)
+			for i, chunk := range entryChunks {
+				part := moodPart{date: section.date.Format("2006-01-02"), text: chunk}
+				if len(entryChunks) > 1 {
+					part.chunk = i + 1
+				}
+				parts = append(parts, part)
+			}
+		}
+		return parts
+	}
+
+	date := ""
+	if dates := documentDates(path, content); len(dates) == 1 {
+		date = dates[0].Format("2006-01-02")
+	}
+	for i, chunk := range chunks {
+		parts = append(parts, moodPart{date: date, chunk: i + 1, text: chunk})
+	}
+	return parts
+}
+
+// scoreMood rates every dated entry (or chunk) of a document. Answers with a
+// score outside the scale or without a label are asked for again and then
+// dropped.
+func scoreMood(path, content string, chunks []string, cfg Config) []MoodEntry {
+	parts := moodParts(path, content, chunks, cfg)
+	texts := make([]string, len(parts))
+	for i, part := range parts {
+		texts[i] = part.text
+	}
+	scores := extractPerChunk(path, "mood", texts, cfg, func(text string) (*MoodEntry, error) {
+		var result struct {
+			Score *int   `json:"score"`
+			Label string `json:"label"`
+		}
+		check := func() error {
+			if result.Score == nil || *result.Score < minMood || *result.Score > maxMood {
+				return fmt.Errorf("score must be an integer from %d to %d", minMood, maxMood)
+			}
+			result.Label = strings.Join(strings.Fields(result.Label), " ")
+			if result.Label == "" || len(strings.Fields(result.Label)) > 3 {
+				return errors.New("label must have one to three words")
+			}
+			return nil
+		}
+		if err := askJSON(buildMoodPrompt(text), &result, check); err != nil {
+			return nil, err
+		}
+		return &MoodEntry{Score: *result.Score, Label: result.Label}, nil
+	})
+	var entries []MoodEntry
+	for i, score := range scores {
+		if score == nil {
+			continue
+		}
+		score.Date, score.Chunk = parts[i].date, parts[i].chunk
+		entries = append(entries, *score)
+	}
+	return entries
+}
+
+// writeMoodTimelines collects the dated mood entries stored in the sidecars of
+// plans into _mood_YYYY.csv and _mood_YYYY.md in the root path, one pair per
+// year. It reports whether an error occurred.
+func writeMoodTimelines(plans []string, cfg Config) bool {
+	type row struct {
+		entry MoodEntry
+		path  string
+	}
+	years := make(map[string][]row)
+	for _, path := range plans {
+		meta, err := readSummaryMeta(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range meta.Mood {
+			if entry.Date != "" {
+				years[entry.Date[:4]] = append(years[entry.Date[:4]], row{entry: entry, path: path})
+			}
+		}
+	}
+
+	yearKeys := make([]string, 0, len(years))
+	for year := range years {
+		yearKeys = append(yearKeys, year)
+	}
+	sort.Strings(yearKeys)
+
+	hadError := false
+	for _, year := range yearKeys {
+		rows := years[year]
+		sort.Slice(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.entry.Date != b.entry.Date {
+				return a.entry.Date < b.entry.Date
+			}
+			if a.path != b.path {
+				return a.path < b.path
+			}
+			return a.entry.Chunk < b.entry.Chunk
+		})
+
+		var csvData bytes.Buffer
+		w := csv.NewWriter(&csvData)
+		w.Write([]string{"date", "score", "label", "source", "chunk"})
+		var md strings.Builder
+		fmt.Fprintf(&md, "# Stimmung %s\n\n| Datum | Wert | Stimmung | Eintrag |\n|---|---:|---|---|\n", year)
+		var months []string
+		monthly := make(map[string][]int)
+		for _, r := range rows {
+			chunk := ""
+			if r.entry.Chunk > 0 {
+				chunk = fmt.Sprint(r.entry.Chunk)
+			}
+			w.Write([]string{r.entry.Date, fmt.Sprint(r.entry.Score), r.entry.Label, filepath.ToSlash(displayPath(r.path, cfg.RootDir)), chunk})
+			fmt.Fprintf(&md, "| %s | %+d | %s | %s |\n", r.entry.Date, r.entry.Score, tableCell(r.entry.Label), tableCell(markdownLink(r.path, cfg.RootDir)))
+			month := r.entry.Date[:7]
+			if monthly[month] == nil {
+				months = append(months, month)
+			}
+			monthly[month] = append(monthly[month], r.entry.Score)
+		}
+		w.Flush()
+
+		md.WriteString("\n## Monatsmittel\n\n| Monat | Mittel | Einträge |\n|---|---:|---:|\n")
+		for _, month := range months {
+			sum := 0
+			for _, score := range monthly[month] {
+				sum += score
+			}
+			fmt.Fprintf(&md, "| %s | %+.1f | %d |\n", month, float64(sum)/float64(len(monthly[month])), len(monthly[month]))
+		}
+
+		base := filepath.Join(cfg.RootDir, "_mood_"+year)
+		if writeReport(base+".csv", csvData.String(), cfg) {
+			hadError = true
+		}
+		if writeReport(base+".md", md.String(), cfg) {
+			hadError = true
+		}
+	}
+	return hadError
+}
+
+// tableCell makes s safe for a Markdown table cell: line breaks and runs of
+// whitespace become single spaces and pipes are escaped.
+func tableCell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestTableCell(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"gelöst", "gelöst"},
+		{"froh | müde", `froh \| müde`},
+		{"erst angespannt,\ndann\r\n  erleichtert", "erst angespannt, dann erleichtert"},
+		{"  ", ""},
+		{"[a|b.md](a%7Cb.md)", `[a\|b.md](a%7Cb.md)`},
+	}
+	for _, tt := range tests {
+		if got := tableCell(tt.in); got != tt.want {
+			t.Errorf("tableCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// onThisDayName is the default note written by the onthisday subcommand.
+const onThisDayName = "_on_this_day.md"
+
+// strategyOnThisDay names the onthisday note in its footer.
+const strategyOnThisDay = "on this day"
+
+// germanMonths are the month names used in the note heading.
+var germanMonths = []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}
+
+// runOnThisDay implements the onthisday subcommand: it collects what the
+// collection holds about one calendar day in earlier years and writes a short
+// retrospective note about it.
+func runOnThisDay(args []string) int {
+	flags := flag.NewFlagSet("onthisday", flag.ExitOnError)
+	dateFlag := flags.String("date", "", "Day to look back on, YYYY-MM-DD (default today)")
+	output := flags.String("output", "", "File to write the note to, relative to the root path (default onthisday.output, "+onThisDayName+")")
+	cfg := parseFlags(flags, args, "chief-summarizer onthisday [flags] <root-path>",
+		"host", "model", "host-cooldown", "request-timeout", "chunk-size", "chunk-overlap", "force", "dry-run",
+		"disable-validation", "validation-retries", "min-summary-length", "max-summary-length", "lock-name", "wait")
+	if *output != "" && *output != cfg.OnThisDayFile {
+		// Runs only skip the configured note; any other file is a source to them.
+		errorf("WARN -output %s differs from onthisday.output (%s); runs will summarize it unless onthisday.output is set to it\n", *output, cfg.OnThisDayFile)
+		cfg.OnThisDayFile = *output
+	}
+
+	day := time.Now()
+	if *dateFlag != "" {
+		t, ok := findDate(*dateFlag)
+		if !ok {
+			errorf("ERR  invalid -date %q (want YYYY-MM-DD)\n", *dateFlag)
+			return 1
+		}
+		day = t
+	}
+	target := onThisDayPath(cfg)
+	display := displayPath(target, cfg.RootDir)
+
+	lockFile, err := acquireLock(cfg)
+	if err != nil {
+		errorf("ERR  %v\n", err)
+		return 1
+	}
+	defer releaseLock(lockFile)
+
+	plans, hadError := discoverFiles(cfg)
+	sort.Strings(plans)
+	inputs := collectOnThisDay(plans, day, cfg)
+	hash := hashBytes([]byte(day.Format("2006-01-02") + "\n\n" + strings.Join(inputs, "\n\n")))
+	switch {
+	case !cfg.Force && upToDate(target, hash):
+		if cfg.Verbose {
+			statusf(cfg, "SKIP %s (note up to date)\n", display)
+		}
+	case cfg.DryRun:
+		statusf(cfg, "DRY  %s (would create from %d entries)\n", display, len(inputs))
+	default:
+		if err := writeOnThisDay(target, day, inputs, hash, cfg); err != nil {
+			errorf("ERR  %s (%v)\n", display, err)
+			return 1
+		}
+		statusf(cfg, "OK   %s (%d entries)\n", display, len(inputs))
+	}
+	if hadError {
+		return 1
+	}
+	return 0
+}
+
+// onThisDayPath returns the location of the onthisday note of cfg.
+func onThisDayPath(cfg Config) string {
+	if filepath.IsAbs(cfg.OnThisDayFile) {
+		return cfg.OnThisDayFile
+	}
+	return filepath.Join(cfg.RootDir, cfg.OnThisDayFile)
+}
+
+// isOnThisDayNote reports whether path is the onthisday note of cfg.
+func isOnThisDayNote(path string, cfg Config) bool {
+	note, err := filepath.Abs(onThisDayPath(cfg))
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	return err == nil && abs == note
+}
+
+// writeOnThisDay writes the retrospective note for day built from inputs.
+// Without inputs it writes a short notice instead, so a note from an earlier
+// day does not linger.
+func writeOnThisDay(target string, day time.Time, inputs []string, hash string, cfg Config) error {
+	heading := fmt.Sprintf("# An diesem Tag: %d. %s\n\n", day.Day(), germanMonths[day.Month()-1])
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		// The footer lets upToDate recognize the notice on the next run. No
+		// model is asked, so none is recorded unless one was passed.
+		if cfg.Model == "" {
+			cfg.Model = "none"
+		}
+		footer := buildSummaryFooter(time.Now(), 0, 0, strategyOnThisDay, cfg)
+		output := heading + "Zu diesem Tag gibt es keine Einträge aus früheren Jahren." + inputsMarker(hash) + footer + "\n"
+		return writeFileAtomic(target, []byte(output), 0o644)
+	}
+
+	httpClient.Timeout = cfg.RequestTimeout
+	ollamaPool = newHostPool(cfg.Hosts, cfg.HostCooldown, cfg)
+	model, err := ollamaPool.checkAll()
+	if err != nil {
+		return fmt.Errorf("model selection failed: %w", err)
+	}
+	cfg.Model = model
+
+	start := time.Now()
+	joined := strings.Join(inputs, "\n\n")
+	lengthCategory := lengthCategoryFromRunes(len([]rune(joined)))
+	prompts := mergePrompts{
+		intermediate: buildDigestIntermediatePrompt,
+		final:        func(inputs []string) string { return buildOnThisDayPrompt(inputs, day, lengthCategory) },
+		unit:         "entry",
+		units:        "entries",
+	}
+	summary, err := mergeTree(target, inputs, prompts, detectLanguage(joined), nil, cfg)
+	if err != nil {
+		return err
+	}
+	generatedAt := time.Now()
+	footer := buildSummaryFooter(generatedAt, generatedAt.Sub(start), len(inputs), strategyOnThisDay, cfg)
+	output := heading + stripThinkBlocks(summary) + inputsMarker(hash) + footer + "\n"
+	return writeFileAtomic(target, []byte(output), 0o644)
+}
+
+// collectOnThisDay returns, oldest first, what the documents in plans hold
+// about the calendar day of day in earlier years: the sections under dated
+// headings of multi-entry diaries, and the summaries of documents dated that
+// day by file name or frontmatter.
+func collectOnThisDay(plans []string, day time.Time, cfg Config) []string {
+	sameDay := func(t time.Time) bool {
+		return t.Month() == day.Month() && t.Day() == day.Day() && t.Year() < day.Year()
+	}
+	var entries []digestInput
+	for _, path := range plans {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		content := string(data)
+		label := displayPath(path, cfg.RootDir)
+		if sections := datedSections(content); len(sections) > 1 {
+			for _, section := range sections {
+				if !sameDay(section.date) || section.text == "" {
+					continue
+				}
+				text := section.text
+				if runes := []rune(text); len(runes) > cfg.ChunkSize {
+					text = string(runes[:cfg.ChunkSize]) + " …"
+				}
+				entries = append(entries, digestInput{date: section.date, text: fmt.Sprintf("%s (%s):\n%s", label, section.date.Format("2006-01-02"), text)})
+			}
+			continue
+		}
+		dates := documentDates(path, content)
+		if len(dates) != 1 || !sameDay(dates[0]) {
+			continue
+		}
+		summary, err := os.ReadFile(summaryFilename(path))
+		if err != nil || !summaryComplete(summaryFilename(path)) {
+			if cfg.Verbose {
+				statusf(cfg, "SKIP %s (no summary yet)\n", label)
+			}
+			continue
+		}
+		entries = append(entries, digestInput{date: dates[0], text: fmt.Sprintf("%s (%s):\n%s", label, dates[0].Format("2006-01-02"), summaryBody(string(summary)))})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].date.Equal(entries[j].date) {
+			return entries[i].date.Before(entries[j].date)
+		}
+		return entries[i].text < entries[j].text
+	})
+	inputs := make([]string, len(entries))
+	for i, entry := range entries {
+		inputs[i] = entry.text
+	}
+	return inputs
+}
+
+func buildOnThisDayPrompt(inputs []string, day time.Time, lengthCategory string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that writes diary retrospectives in the original language of the source texts.\n\n")
+	b.WriteString(fmt.Sprintf("Task:\n- You receive diary entries (or their summaries) written on %d %s in earlier years, oldest first, labelled with file and date.\n", day.Day(), day.Month()))
+	b.WriteString("- Write a short \"on this day\" retrospective: what happened on this day in each year, and what changed or stayed the same over the years.\n- Name the year of every event you mention.\n- Maintain the SAME LANGUAGE as the inputs (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the inputs.\n- Be factual and do NOT add information that is not in the inputs.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
+	b.WriteString(finalOutputFormat)
+	b.WriteString("Do NOT add any footer or metadata lines; the system will append them.\n\n")
+	b.WriteString(fmt.Sprintf("Content length category: %s.\n\n", lengthCategory))
+	b.WriteString("Input:\nThe following are the entries for this day:\n\n---\n")
+	for i, input := range inputs {
+		b.WriteString(fmt.Sprintf("Entry %d: %s\n\n", i+1, input))
+	}
+	b.WriteString("---\n\nNow produce ONLY the markdown retrospective as specified above.\nDo not add any intro text or explanations around it.\n")
+	return b.String()
+}
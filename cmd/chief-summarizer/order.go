@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported values for -order.
+const (
+	orderRandom        = "random"
+	orderNewestFirst   = "newest-first"
+	orderOldestFirst   = "oldest-first"
+	orderSmallestFirst = "smallest-first"
+	orderLargestFirst  = "largest-first"
+	orderPath          = "path"
+)
+
+var processingOrders = []string{
+	orderRandom,
+	orderNewestFirst,
+	orderOldestFirst,
+	orderSmallestFirst,
+	orderLargestFirst,
+	orderPath,
+}
+
+func validateOrder(order string) error {
+	for _, known := range processingOrders {
+		if order == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown order %q (expected one of: %s)", order, strings.Join(processingOrders, ", "))
+}
+
+// orderPlans sorts plans in place according to order. For the random order a
+// non-zero seed makes the shuffle reproducible; zero seeds from the clock.
+func orderPlans(plans []string, order string, seed int64) {
+	switch order {
+	case orderNewestFirst, orderOldestFirst, orderSmallestFirst, orderLargestFirst:
+		infos := make(map[string]os.FileInfo, len(plans))
+		for _, path := range plans {
+			if info, err := os.Stat(path); err == nil {
+				infos[path] = info
+			}
+		}
+		key := func(path string) int64 {
+			info, ok := infos[path]
+			if !ok {
+				return 0
+			}
+			if order == orderNewestFirst || order == orderOldestFirst {
+				return info.ModTime().UnixNano()
+			}
+			return info.Size()
+		}
+		descending := order == orderNewestFirst || order == orderLargestFirst
+		sort.SliceStable(plans, func(i, j int) bool {
+			ki, kj := key(plans[i]), key(plans[j])
+			if ki == kj {
+				return plans[i] < plans[j]
+			}
+			if descending {
+				return ki > kj
+			}
+			return ki < kj
+		})
+	case orderPath:
+		sort.Strings(plans)
+	default:
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		// Sort first so a fixed seed yields the same order regardless of walk order.
+		sort.Strings(plans)
+		rnd := rand.New(rand.NewSource(seed))
+		rnd.Shuffle(len(plans), func(i, j int) {
+			plans[i], plans[j] = plans[j], plans[i]
+		})
+	}
+}
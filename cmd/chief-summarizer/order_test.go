@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrderPlans(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2024, 3, 12, 12, 0, 0, 0, time.UTC)
+	files := []struct {
+		name    string
+		size    int
+		modTime time.Time
+	}{
+		{"a.md", 30, base.Add(2 * time.Hour)},
+		{"b.md", 10, base},
+		{"c.md", 20, base.Add(time.Hour)},
+		{"d.md", 20, base.Add(time.Hour)},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, f.modTime, f.modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	plans := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		return paths
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		// c.md and d.md tie on both size and time and stay in path order.
+		{orderNewestFirst, plans("a.md", "c.md", "d.md", "b.md")},
+		{orderOldestFirst, plans("b.md", "c.md", "d.md", "a.md")},
+		{orderSmallestFirst, plans("b.md", "c.md", "d.md", "a.md")},
+		{orderLargestFirst, plans("a.md", "c.md", "d.md", "b.md")},
+		{orderPath, plans("a.md", "b.md", "c.md", "d.md")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			got := plans("d.md", "b.md", "a.md", "c.md")
+			orderPlans(got, tt.order, 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderPlans(%s) = %q, want %q", tt.order, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderPlansMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present.md")
+	if err := os.WriteFile(present, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.md")
+	got := []string{present, missing}
+	orderPlans(got, orderSmallestFirst, 0)
+	if want := []string{missing, present}; !reflect.DeepEqual(got, want) {
+		t.Errorf("orderPlans() = %q, want %q", got, want)
+	}
+}
+
+func TestOrderPlansRandomSeed(t *testing.T) {
+	names := []string{"a.md", "b.md", "c.md", "d.md", "e.md", "f.md", "g.md", "h.md"}
+	first := append([]string(nil), names...)
+	orderPlans(first, orderRandom, 42)
+
+	// The same seed gives the same order, whatever order the walk produced.
+	reversed := append([]string(nil), names...)
+	sort.Sort(sort.Reverse(sort.StringSlice(reversed)))
+	orderPlans(reversed, orderRandom, 42)
+	if !reflect.DeepEqual(first, reversed) {
+		t.Errorf("seed 42 gave %q and %q", first, reversed)
+	}
+
+	sorted := append([]string(nil), first...)
+	sort.Strings(sorted)
+	if !reflect.DeepEqual(sorted, names) {
+		t.Errorf("orderPlans() = %q, not a permutation of %q", first, names)
+	}
+
+	other := append([]string(nil), names...)
+	orderPlans(other, orderRandom, 43)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 42 and 43 gave the same order %q", first)
+	}
+}
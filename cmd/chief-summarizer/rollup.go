@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// folderSummaryName is the rollup written into every folder by -rollup.
+	folderSummaryName = "_folder_summary.md"
+	strategyRollup    = "rollup"
+)
+
+// inputsMarkerPattern finds the hash of the inputs a rollup was built from.
+var inputsMarkerPattern = regexp.MustCompile(`<!-- chief-summarizer inputs: ([0-9a-f]+) -->`)
+
+// inputsMarker records the hash of a generated note's inputs so it is only
+// regenerated when one of them changes.
+func inputsMarker(hash string) string {
+	return fmt.Sprintf("\n\n<!-- chief-summarizer inputs: %s -->", hash)
+}
+
+// upToDate reports whether path is a complete generated note built from
+// inputs with the given hash.
+func upToDate(path, hash string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil || !summaryComplete(path) {
+		return false
+	}
+	m := inputsMarkerPattern.FindStringSubmatch(string(data))
+	return m != nil && m[1] == hash
+}
+
+// ultraShortSection returns the "## Ultra-Kurzfassung" section of a summary,
+// or its first paragraph when the heading is missing.
+func ultraShortSection(summary string) string {
+	summary = summaryBody(summary)
+	if start := strings.Index(summary, requiredHeadings[0]); start >= 0 {
+		section := summary[start+len(requiredHeadings[0]):]
+		if end := strings.Index(section, "\n## "); end >= 0 {
+			section = section[:end]
+		}
+		return strings.TrimSpace(section)
+	}
+	for _, paragraph := range strings.Split(summary, "\n\n") {
+		if p := strings.TrimSpace(paragraph); p != "" && !strings.HasPrefix(p, "#") {
+			return p
+		}
+	}
+	return ""
+}
+
+// readUltraShort returns the ultra-short section of the complete summary at
+// path.
+func readUltraShort(path string) (string, bool) {
+	if !summaryComplete(path) {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	short := ultraShortSection(string(data))
+	return short, short != ""
+}
+
+// rollupFolders writes a _folder_summary.md into every folder that holds a
+// summarized source or a summarized subfolder, deepest folders first, so each
+// rollup is built from the ultra-short sections of its files and subfolders.
+// A folder is only regenerated when those inputs changed. It reports whether
+// an error occurred.
+func rollupFolders(plans []string, cfg Config) bool {
+	root := filepath.Clean(cfg.RootDir)
+	files := make(map[string][]string)
+	children := make(map[string][]string)
+	seen := map[string]bool{root: true}
+	for _, path := range plans {
+		if !summaryComplete(summaryFilename(path)) {
+			continue
+		}
+		dir := filepath.Dir(path)
+		files[dir] = append(files[dir], path)
+		for dir != root && !seen[dir] {
+			seen[dir] = true
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			children[parent] = append(children[parent], dir)
+			dir = parent
+		}
+	}
+	if len(files) == 0 {
+		return false
+	}
+
+	dirs := make([]string, 0, len(seen))
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], string(filepath.Separator)), strings.Count(dirs[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	hadError := false
+	available := make(map[string]bool)
+	for _, dir := range dirs {
+		var inputs []string
+		sort.Strings(files[dir])
+		for _, path := range files[dir] {
+			if short, ok := readUltraShort(summaryFilename(path)); ok {
+				inputs = append(inputs, fmt.Sprintf("%s:\n%s", filepath.Base(path), short))
+			}
+		}
+		sort.Strings(children[dir])
+		for _, child := range children[dir] {
+			if short, ok := readUltraShort(filepath.Join(child, folderSummaryName)); ok {
+				inputs = append(inputs, fmt.Sprintf("%s/:\n%s", filepath.Base(child), short))
+			} else if available[child] {
+				inputs = append(inputs, fmt.Sprintf("%s/:\n(new folder summary)", filepath.Base(child)))
+			}
+		}
+		if len(inputs) == 0 {
+			continue
+		}
+
+		display := displayPath(dir, cfg.RootDir)
+		target := filepath.Join(dir, folderSummaryName)
+		hash := hashBytes([]byte(strings.Join(inputs, "\n\n")))
+		if !cfg.Force && upToDate(target, hash) {
+			if cfg.Verbose {
+				statusf(cfg, "SKIP %s (folder summary up to date)\n", display)
+			}
+			available[dir] = true
+			continue
+		}
+		if cfg.DryRun {
+			statusf(cfg, "DRY  %s (would create %s from %d inputs)\n", display, displayPath(target, cfg.RootDir), len(inputs))
+			available[dir] = true
+			continue
+		}
+		statusf(cfg, "ROLL %s (%d inputs)\n", display, len(inputs))
+		if err := writeFolderSummary(dir, target, inputs, hash, cfg); err != nil {
+			errorf("ERR  %s (folder summary: %v)\n", display, err)
+			hadError = true
+			continue
+		}
+		available[dir] = true
+		statusf(cfg, "OK   %s -> %s\n", display, displayPath(target, cfg.RootDir))
+	}
+	return hadError
+}
+
+func writeFolderSummary(dir, target string, inputs []string, hash string, cfg Config) error {
+	start := time.Now()
+	joined := strings.Join(inputs, "\n\n")
+	lengthCategory := lengthCategoryFromRunes(len([]rune(joined)))
+	prompts := mergePrompts{
+		intermediate: buildFolderIntermediatePrompt,
+		final:        func(inputs []string) string { return buildFolderFinalPrompt(inputs, lengthCategory) },
+		unit:         "summary",
+		units:        "summaries",
+	}
+	summary, err := mergeTree(dir, inputs, prompts, detectLanguage(joined), nil, cfg)
+	if err != nil {
+		return err
+	}
+	generatedAt := time.Now()
+	footer := buildSummaryFooter(generatedAt, generatedAt.Sub(start), len(inputs), strategyRollup, cfg)
+	output := stripThinkBlocks(summary) + inputsMarker(hash) + footer + "\n"
+	return writeFileAtomic(target, []byte(output), 0o644)
+}
+
+func buildFolderIntermediatePrompt(inputs []string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that consolidates summaries into a concise overview while keeping the original language.\n\n")
+	b.WriteString("Task:\n- Each input below is the short summary of one document or subfolder of the same folder in a personal notes collection, labelled with its name.\n- Merge them into a single partial overview of these entries.\n- Maintain the SAME LANGUAGE as the inputs (usually German).\n- Keep the names of documents and subfolders where they help to find a topic.\n- Keep important names, dates and numbers.\n- Use 1–2 short paragraphs OR 3–5 bullet points.\n- Do NOT add headings, intro text, or any sections labelled 'Thinking'.\n\n")
+	b.WriteString("Input summaries:\n---\n")
+	for i, input := range inputs {
+		b.WriteString(fmt.Sprintf("Entry %d: %s\n\n", i+1, input))
+	}
+	b.WriteString("---\n\nReturn ONLY the consolidated overview, nothing else.\n")
+	return b.String()
+}
+
+func buildFolderFinalPrompt(inputs []string, lengthCategory string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that creates structured overviews in the original language of the source texts.\n\n")
+	b.WriteString("Task:\n- You receive short summaries of the documents and subfolders of ONE folder in a personal notes collection (often a diary), labelled with their names.\n- Write ONE overview of what the folder contains as a whole.\n- Group related entries by topic or period and name the documents or subfolders that cover them.\n- Maintain the SAME LANGUAGE as the inputs (usually German).\n- Keep important names, dates and numbers.\n- Be neutral and factual and do NOT add information that is not in the inputs.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
+	b.WriteString(finalOutputFormat)
+	b.WriteString("Do NOT add any footer or metadata lines; the system will append them.\n\n")
+	b.WriteString(fmt.Sprintf("Folder content length category: %s.\n\n", lengthCategory))
+	b.WriteString("Input:\nThe following are the summaries of the folder's entries:\n\n---\n")
+	for i, input := range inputs {
+		b.WriteString(fmt.Sprintf("Entry %d: %s\n\n", i+1, input))
+	}
+	b.WriteString("---\n\nNow produce ONLY the markdown overview as specified above.\nDo not add any intro text or explanations around it.\n")
+	return b.String()
+}
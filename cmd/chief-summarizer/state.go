@@ -0,0 +1,237 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const stateFileVersion = 1
+
+// maxRetryBackoff caps the exponential retry delay for failing files.
+const maxRetryBackoff = 7 * 24 * time.Hour
+
+// FileState is the persisted history of a single source document.
+type FileState struct {
+	Hash            string    `json:"hash"`
+	LastAttempt     time.Time `json:"last_attempt,omitzero"`
+	LastSuccess     time.Time `json:"last_success,omitzero"`
+	Failures        int       `json:"failures,omitempty"`
+	LastError       string    `json:"last_error,omitempty"`
+	Model           string    `json:"model,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+}
+
+// StateDB is a small JSON-backed store of per-file run history, keyed by
+// absolute source path.
+type StateDB struct {
+	mu    sync.Mutex
+	path  string
+	files map[string]*FileState
+	dirty map[string]bool
+}
+
+type stateFile struct {
+	Version int                   `json:"version"`
+	Files   map[string]*FileState `json:"files"`
+}
+
+// defaultStatePath returns $XDG_STATE_HOME/chief-summarizer/state.json,
+// falling back to ~/.local/state.
+func defaultStatePath(homeDir string) string {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		base = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(base, "chief-summarizer", "state.json")
+}
+
+// openState loads the state database at path. A missing file yields an empty
+// database; a corrupt one is returned empty together with the decode error.
+func openState(path string) (*StateDB, error) {
+	db := &StateDB{
+		path:  path,
+		files: make(map[string]*FileState),
+		dirty: make(map[string]bool),
+	}
+	files, err := readStateFile(path)
+	if err != nil {
+		return db, err
+	}
+	db.files = files
+	return db, nil
+}
+
+func readStateFile(path string) (map[string]*FileState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(map[string]*FileState), nil
+	}
+	if err != nil {
+		return make(map[string]*FileState), err
+	}
+	var sf stateFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return make(map[string]*FileState), err
+	}
+	if sf.Files == nil {
+		sf.Files = make(map[string]*FileState)
+	}
+	return sf.Files, nil
+}
+
+func stateKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// Get returns a copy of the entry for path, or nil if none is recorded.
+func (db *StateDB) Get(path string) *FileState {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	entry, ok := db.files[stateKey(path)]
+	if !ok {
+		return nil
+	}
+	copied := *entry
+	return &copied
+}
+
+// Entries returns a snapshot of all recorded entries keyed by absolute path.
+func (db *StateDB) Entries() map[string]FileState {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	out := make(map[string]FileState, len(db.files))
+	for key, entry := range db.files {
+		out[key] = *entry
+	}
+	return out
+}
+
+// RecordAttempt stores the outcome of summarizing path. A change in content
+// hash resets the failure history. Host problems (refused connections,
+// timeouts, 5xx responses, a missing model; see hostFailure) are recorded but
+// don't count as failures of the file.
+func (db *StateDB) RecordAttempt(path, hash, model string, at time.Time, duration time.Duration, runErr error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	key := stateKey(path)
+	entry, ok := db.files[key]
+	if !ok || entry.Hash != hash {
+		entry = &FileState{Hash: hash}
+		db.files[key] = entry
+	}
+	entry.LastAttempt = at
+	entry.Model = model
+	entry.DurationSeconds = duration.Seconds()
+	if runErr != nil {
+		if !hostFailure(runErr) {
+			entry.Failures++
+		}
+		entry.LastError = runErr.Error()
+	} else {
+		entry.Failures = 0
+		entry.LastError = ""
+		entry.LastSuccess = at
+	}
+	db.dirty[key] = true
+}
+
+// Forget removes path from the database.
+func (db *StateDB) Forget(path string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	key := stateKey(path)
+	delete(db.files, key)
+	db.dirty[key] = true
+}
+
+// Save merges the entries changed in this process into the file on disk and
+// writes it atomically, so concurrent runs on other roots don't lose updates.
+func (db *StateDB) Save() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.dirty) == 0 {
+		return nil
+	}
+	onDisk, err := readStateFile(db.path)
+	if err != nil {
+		onDisk = make(map[string]*FileState)
+	}
+	for key := range db.dirty {
+		if entry, ok := db.files[key]; ok {
+			onDisk[key] = entry
+		} else {
+			delete(onDisk, key)
+		}
+	}
+	data, err := json.MarshalIndent(stateFile{Version: stateFileVersion, Files: onDisk}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(db.path), 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(db.path, data, 0o644); err != nil {
+		return err
+	}
+	db.files = onDisk
+	db.dirty = make(map[string]bool)
+	return nil
+}
+
+// retryDecision reports whether a previously failing file should be skipped.
+// It returns a short reason for the SKIP line when it should.
+func retryDecision(entry *FileState, hash string, maxFailures int, backoff time.Duration, now time.Time) (bool, string) {
+	if entry == nil || entry.Failures == 0 || entry.Hash != hash {
+		return false, ""
+	}
+	if maxFailures > 0 && entry.Failures >= maxFailures {
+		return true, fmt.Sprintf("failed %d times; edit the file or use -force to retry", entry.Failures)
+	}
+	if backoff <= 0 {
+		return false, ""
+	}
+	delay := backoff
+	for i := 1; i < entry.Failures && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	next := entry.LastAttempt.Add(delay)
+	if now.Before(next) {
+		return true, "retry backoff until " + next.Format("2006-01-02 15:04")
+	}
+	return false, ""
+}
+
+// hashBytes returns the hex SHA-256 of data.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the hex SHA-256 of the file's content.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
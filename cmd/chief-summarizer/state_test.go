@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDecision(t *testing.T) {
+	now := time.Date(2024, 3, 12, 12, 0, 0, 0, time.UTC)
+	failing := func(failures int, ago time.Duration) *FileState {
+		return &FileState{Hash: "h", Failures: failures, LastAttempt: now.Add(-ago)}
+	}
+	tests := []struct {
+		name        string
+		entry       *FileState
+		hash        string
+		maxFailures int
+		backoff     time.Duration
+		wantSkip    bool
+		wantReason  string
+	}{
+		{name: "no history", entry: nil, hash: "h", maxFailures: 5, backoff: time.Hour},
+		{name: "last attempt succeeded", entry: &FileState{Hash: "h"}, hash: "h", maxFailures: 5, backoff: time.Hour},
+		{name: "source changed since the failure", entry: failing(5, 0), hash: "other", maxFailures: 5, backoff: time.Hour},
+		{
+			name: "too many failures", entry: failing(5, 30*24*time.Hour), hash: "h", maxFailures: 5, backoff: time.Hour,
+			wantSkip: true, wantReason: "failed 5 times; edit the file or use -force to retry",
+		},
+		{name: "max failures 0 never gives up", entry: failing(9, 30*24*time.Hour), hash: "h", maxFailures: 0, backoff: time.Hour},
+		{name: "backoff 0 retries every run", entry: failing(1, 0), hash: "h", maxFailures: 5},
+		{
+			name: "first failure waits one backoff", entry: failing(1, 30*time.Minute), hash: "h", maxFailures: 5, backoff: time.Hour,
+			wantSkip: true, wantReason: "retry backoff until 2024-03-12 12:30",
+		},
+		{name: "first failure after the backoff", entry: failing(1, time.Hour), hash: "h", maxFailures: 5, backoff: time.Hour},
+		{
+			name: "backoff doubles per failure", entry: failing(3, 3*time.Hour), hash: "h", maxFailures: 5, backoff: time.Hour,
+			wantSkip: true, wantReason: "retry backoff until 2024-03-12 13:00",
+		},
+		{name: "third failure after four backoffs", entry: failing(3, 4*time.Hour), hash: "h", maxFailures: 5, backoff: time.Hour},
+		{
+			name: "backoff is capped", entry: failing(20, 6*24*time.Hour), hash: "h", maxFailures: 0, backoff: time.Hour,
+			wantSkip: true, wantReason: "retry backoff until 2024-03-13 12:00",
+		},
+		{name: "capped backoff expires", entry: failing(20, maxRetryBackoff), hash: "h", maxFailures: 0, backoff: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip, reason := retryDecision(tt.entry, tt.hash, tt.maxFailures, tt.backoff, now)
+			if skip != tt.wantSkip || reason != tt.wantReason {
+				t.Errorf("retryDecision() = %v, %q, want %v, %q", skip, reason, tt.wantSkip, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// footerPattern matches the metadata line appended by buildSummaryFooter.
+var footerPattern = regexp.MustCompile(`_Generated automatically on (.+?) by Chief Summarizer \(AI v([^)]+)\) \| Model: ([^|]+?) \| Chunks: (\d+)`)
+
+// summaryFooter holds the metadata parsed from a summary footer.
+type summaryFooter struct {
+	GeneratedAt string
+	Version     string
+	Model       string
+	Chunks      string
+}
+
+// parseSummaryFooter extracts the footer metadata from summary content.
+func parseSummaryFooter(content string) (summaryFooter, bool) {
+	matches := footerPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return summaryFooter{}, false
+	}
+	m := matches[len(matches)-1]
+	return summaryFooter{GeneratedAt: m[1], Version: m[2], Model: strings.TrimSpace(m[3]), Chunks: m[4]}, true
+}
+
+// fileStatus describes the summarization state of a single source document.
+type fileStatus struct {
+	Path            string
+	Summarized      bool
+	Stale           bool
+	Incomplete      bool
+	Empty           bool
+	Footer          summaryFooter
+	HasFooter       bool
+	Checkpoint      bool
+	CheckpointDone  int
+	CheckpointTotal int
+	// CheckpointOutdated is set when the checkpoint no longer matches the
+	// source and will be discarded by the next run.
+	CheckpointOutdated bool
+	State              *FileState
+}
+
+func inspectFile(path string, cfg Config, state *StateDB) fileStatus {
+	st := fileStatus{Path: path, State: state.Get(path)}
+	srcInfo, srcErr := os.Stat(path)
+	src, readErr := os.ReadFile(path)
+	trimmed := strings.TrimSpace(string(src))
+	if readErr == nil && trimmed == "" {
+		st.Empty = true
+	}
+
+	if info, err := os.Stat(summaryFilename(path)); err == nil {
+		st.Summarized = true
+		if srcErr == nil && srcInfo.ModTime().After(info.ModTime()) {
+			st.Stale = true
+		}
+		if data, err := os.ReadFile(summaryFilename(path)); err == nil {
+			st.Footer, st.HasFooter = parseSummaryFooter(string(data))
+		}
+		st.Incomplete = !summaryComplete(summaryFilename(path))
+	}
+
+	if cp, err := readCheckpoint(chunksFilename(path)); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		st.Checkpoint = true
+		st.CheckpointDone = cp.Done
+		size, overlap := cfg.ChunkSize, cfg.ChunkOverlap
+		if cp.ChunkSize > 0 {
+			size, overlap = cp.ChunkSize, cp.ChunkOverlap
+		}
+		if readErr == nil {
+			st.CheckpointTotal = len(chunkText(trimmed, size, overlap))
+			st.CheckpointOutdated = cp.Version != checkpointVersion || cp.SourceHash != hashBytes(src)
+		}
+	}
+	return st
+}
+
+// runStatus implements `chief-summarizer status [flags] <root-path>`.
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	cfg := parseFlags(flags, args, "chief-summarizer status [flags] <root-path>", "state-file", "chunk-size", "chunk-overlap")
+
+	state, err := openState(cfg.StatePath)
+	if err != nil {
+		errorf("WARN state file %s unreadable: %v\n", cfg.StatePath, err)
+	}
+
+	plans, hadError := discoverFiles(cfg)
+	sort.Strings(plans)
+
+	var total, summarized, stale, pending, missing, empty, failing int
+	models := make(map[string]int)
+	for _, path := range plans {
+		st := inspectFile(path, cfg, state)
+		display := displayPath(path, cfg.RootDir)
+		total++
+
+		var details []string
+		code := "MISS"
+		switch {
+		case st.Empty && !st.Summarized:
+			code = "EMPT"
+			empty++
+		case st.Summarized && st.Incomplete:
+			code = "BAD "
+			missing++
+		case st.Summarized && st.Stale:
+			code = "STAL"
+			stale++
+			summarized++
+		case st.Summarized:
+			code = "OK  "
+			summarized++
+		case st.Checkpoint:
+			code = "PART"
+			missing++
+		default:
+			missing++
+		}
+		if st.Incomplete {
+			details = append(details, "summary truncated or invalid; next run regenerates it")
+		} else if st.Summarized {
+			if st.HasFooter {
+				details = append(details, fmt.Sprintf("model=%s, v%s", st.Footer.Model, st.Footer.Version))
+				models[st.Footer.Model]++
+			} else {
+				details = append(details, "no footer")
+				models["unknown"]++
+			}
+		}
+		if st.Stale {
+			details = append(details, "source changed after summary")
+		}
+		if st.Checkpoint {
+			pending++
+			if st.CheckpointOutdated {
+				details = append(details, "outdated checkpoint")
+			} else {
+				details = append(details, fmt.Sprintf("checkpoint %d/%d chunks", st.CheckpointDone, st.CheckpointTotal))
+			}
+		}
+		if st.State != nil && st.State.Failures > 0 {
+			failing++
+			details = append(details, fmt.Sprintf("%d failures, last: %s", st.State.Failures, st.State.LastError))
+		}
+
+		if len(details) > 0 {
+			statusf(cfg, "%s %s (%s)\n", code, display, strings.Join(details, "; "))
+		} else {
+			statusf(cfg, "%s %s\n", code, display)
+		}
+	}
+
+	eligible := total - empty
+	coverage := 100.0
+	if eligible > 0 {
+		coverage = float64(summarized) * 100 / float64(eligible)
+	}
+	if !cfg.Quiet {
+		fmt.Println()
+	}
+	fmt.Printf("Files:       %d\n", total)
+	fmt.Printf("Summarized:  %d (%.1f%% of %d non-empty)\n", summarized, coverage, eligible)
+	fmt.Printf("Stale:       %d\n", stale)
+	fmt.Printf("Missing:     %d\n", missing)
+	fmt.Printf("Checkpoints: %d\n", pending)
+	fmt.Printf("Failing:     %d\n", failing)
+	fmt.Printf("Empty:       %d\n", empty)
+	if len(models) > 0 {
+		names := make([]string, 0, len(models))
+		for name := range models {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, models[name]))
+		}
+		fmt.Printf("Models:      %s\n", strings.Join(parts, ", "))
+	}
+
+	if hadError {
+		return 1
+	}
+	return 0
+}
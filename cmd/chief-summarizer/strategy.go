@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Supported values for -strategy.
+const (
+	strategyMapReduce = "map-reduce"
+	strategyRefine    = "refine"
+	strategyStuff     = "stuff"
+)
+
+var summarizationStrategies = []string{strategyMapReduce, strategyRefine, strategyStuff}
+
+// summarizeChunks runs the map step: one summary per chunk, spread over the
+// Ollama hosts. The checkpoint is updated whenever the finished prefix grows
+// so an interrupted run can resume.
+func summarizeChunks(path string, chunks []string, ckpt *checkpointer, cfg Config) ([]string, error) {
+	chunkSummaries := make([]string, len(chunks))
+	done := copy(chunkSummaries, ckpt.cp.Summaries)
+	finished := make([]bool, len(chunks))
+	for idx := 0; idx < done; idx++ {
+		finished[idx] = true
+	}
+
+	var mu sync.Mutex
+	err := runParallel(len(chunks)-done, ollamaPool.capacity(), func(i int) error {
+		idx := done + i
+		statusf(cfg, "CHNK %s (%d/%d)\n", displayPath(path, cfg.RootDir), idx+1, len(chunks))
+		prompt := buildChunkPrompt(chunks[idx])
+		resp, err := callOllama(prompt)
+		if err != nil {
+			return fmt.Errorf("chunk %d summarization failed: %w", idx+1, err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		chunkSummaries[idx] = stripThinkBlocks(resp)
+		finished[idx] = true
+		prefix := ckpt.cp.Done
+		for prefix < len(chunks) && finished[prefix] {
+			prefix++
+		}
+		if prefix > ckpt.cp.Done {
+			ckpt.cp.Done = prefix
+			ckpt.cp.Summaries = append([]string(nil), chunkSummaries[:prefix]...)
+			ckpt.save()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chunkSummaries, nil
+}
+
+// refineChunks folds the chunks into a running summary one at a time, which
+// keeps the narrative order of diaries intact, then formats the result with
+// the final prompt.
+func refineChunks(path string, chunks []string, ckpt *checkpointer, lengthCategory, sourceLang string, cfg Config) (string, error) {
+	display := displayPath(path, cfg.RootDir)
+	running := ""
+	done := 0
+	if len(ckpt.cp.Summaries) == 1 {
+		running = ckpt.cp.Summaries[0]
+		done = ckpt.cp.Done
+	}
+
+	for idx := done; idx < len(chunks); idx++ {
+		var prompt string
+		if idx == 0 {
+			statusf(cfg, "CHNK %s (%d/%d)\n", display, idx+1, len(chunks))
+			prompt = buildChunkPrompt(chunks[idx])
+		} else {
+			statusf(cfg, "RFNE %s (%d/%d)\n", display, idx+1, len(chunks))
+			prompt = buildRefinePrompt(running, chunks[idx])
+		}
+		resp, err := callOllama(prompt)
+		if err != nil {
+			return "", fmt.Errorf("refine step %d failed: %w", idx+1, err)
+		}
+		running = stripThinkBlocks(resp)
+
+		ckpt.cp.Done = idx + 1
+		ckpt.cp.Summaries = []string{running}
+		ckpt.save()
+	}
+
+	statusf(cfg, "FINAL %s (refined %d chunks)\n", display, len(chunks))
+	return finalizeSummary(path, buildFinalPrompt([]string{running}, lengthCategory), sourceLang, cfg)
+}
+
+func buildRefinePrompt(running, chunk string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that maintains a running summary of a long document in its original language.\n\n")
+	b.WriteString("Task:\n- You receive the summary of the document so far and the next excerpt.\n- The document is usually a diary written in the first person.\n- Update the summary so it also covers the new excerpt.\n- Keep the chronological order of events.\n- Maintain the SAME LANGUAGE as the text (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the source.\n- Keep important names, dates and numbers.\n- Do NOT add your own interpretations or new ideas.\n- Do NOT add headings, intro text, or any sections labelled 'Thinking'.\n\n")
+	b.WriteString("Output format:\n- Plain text paragraphs, at most ~400 words in total.\n- Condense older events if needed to stay within the limit.\n\n")
+	b.WriteString("Summary so far:\n---\n")
+	b.WriteString(running)
+	b.WriteString("\n---\n\nNext excerpt:\n---\n")
+	b.WriteString(chunk)
+	b.WriteString("\n---\n\nReturn ONLY the updated summary, nothing else.\n")
+	return b.String()
+}
+
+// buildStuffPrompt asks for the final structured summary directly from the
+// full document, for documents that fit into a single context window.
+func buildStuffPrompt(text, lengthCategory string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that creates structured summaries in the original language of the source text.\n\n")
+	b.WriteString("Task:\n- You receive ONE complete markdown document.\n- The document is usually a diary written in the first person.\n- Summarize it into ONE cohesive summary.\n- Maintain the SAME LANGUAGE as the original text (usually German).\n- Preserve the first-person perspective (Ich-Form) exactly as in the source.\n- Keep important names, dates and numbers.\n- Be neutral and factual.\n- Do NOT include any \"Thinking\" sections or hidden reasoning notes in the response.\n\n")
+	b.WriteString(finalOutputFormat)
+	b.WriteString("Do NOT add any footer or metadata lines; the system will append them.\n\n")
+	b.WriteString(fmt.Sprintf("Original document length category: %s.\n\n", lengthCategory))
+	b.WriteString("Input:\nThe complete document:\n\n---\n")
+	b.WriteString(text)
+	b.WriteString("\n---\n\nNow produce ONLY the markdown summary as specified above.\nDo not add any intro text or explanations around it.\n")
+	return b.String()
+}
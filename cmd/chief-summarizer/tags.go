@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxTags is the most tags proposed per document.
+const maxTags = 5
+
+func buildTagPrompt(summary string, vocabulary []string) string {
+	var b strings.Builder
+	b.WriteString("You are \"Chief Summarizer\", an assistant that classifies notes by topic.\n\n")
+	fmt.Fprintf(&b, "Task:\n- Read the summary of a note below.\n- Propose 1 to %d tags that describe its main topics.\n", maxTags)
+	if len(vocabulary) > 0 {
+		fmt.Fprintf(&b, "- Use ONLY tags from this list: %s.\n", strings.Join(vocabulary, ", "))
+	} else {
+		b.WriteString("- Use short, general tags in the language of the summary, lowercase, with hyphens instead of spaces (e.g. \"arbeit\", \"familie\", \"gesundheit\").\n")
+	}
+	b.WriteString("- Do NOT tag names of people or dates.\n\n")
+	b.WriteString("Respond with JSON only, in exactly this form:\n{\"tags\": [\"arbeit\", \"reisen\"]}\n\nSummary:\n---\n")
+	b.WriteString(summary)
+	b.WriteString("\n---\n")
+	return b.String()
+}
+
+// normalizeTag turns a model tag into the form used in frontmatter: lowercase,
+// without a leading #, words joined by hyphens.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+	return strings.Join(strings.Fields(tag), "-")
+}
+
+// proposeTags asks the model for tags for a finished summary. With a
+// vocabulary, other tags are dropped and an answer without any usable tag is
+// asked for again.
+func proposeTags(path, summary string, cfg Config) ([]string, error) {
+	allowed := make(map[string]bool)
+	for _, tag := range cfg.TagVocabulary {
+		allowed[normalizeTag(tag)] = true
+	}
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+	var tags []string
+	check := func() error {
+		tags = nil
+		for _, tag := range result.Tags {
+			tag = normalizeTag(tag)
+			if tag == "" || containsString(tags, tag) || (len(allowed) > 0 && !allowed[tag]) {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		if len(tags) == 0 {
+			return errors.New("no usable tags in answer")
+		}
+		if len(tags) > maxTags {
+			tags = tags[:maxTags]
+		}
+		return nil
+	}
+	statusf(cfg, "TAGS %s\n", displayPath(path, cfg.RootDir))
+	if err := askJSON(buildTagPrompt(summary, cfg.TagVocabulary), &result, check); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// withTagFrontmatter prepends a frontmatter block with tags to a summary.
+func withTagFrontmatter(summary string, tags []string) string {
+	if len(tags) == 0 {
+		return summary
+	}
+	return fmt.Sprintf("---\ntags: [%s]\n---\n\n%s", strings.Join(tags, ", "), summary)
+}
+
+// addSourceTags returns content with tags merged into the tags of its
+// frontmatter, creating the frontmatter if needed. changed is false when all
+// tags were already present.
+func addSourceTags(content string, tags []string) (updated string, changed bool, err error) {
+	front, body, ok := splitFrontmatter(content)
+	var doc yaml.Node
+	if ok {
+		if err := yaml.Unmarshal([]byte(front), &doc); err != nil {
+			return "", false, fmt.Errorf("parse frontmatter: %w", err)
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", false, errors.New("frontmatter is not a mapping")
+	}
+
+	existing := mappingValue(root, "tags")
+	if existing == nil {
+		existing = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tags"}, existing)
+	}
+	if existing.Kind == yaml.ScalarNode {
+		// Obsidian also accepts "tags: a, b" and "tags: a b".
+		items := strings.FieldsFunc(existing.Value, func(r rune) bool { return r == ',' || r == ' ' })
+		*existing = yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range items {
+			existing.Content = append(existing.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+		}
+	}
+	if existing.Kind != yaml.SequenceNode {
+		return "", false, errors.New("frontmatter tags are not a list")
+	}
+	present := make(map[string]bool)
+	for _, item := range existing.Content {
+		present[normalizeTag(item.Value)] = true
+	}
+	for _, tag := range tags {
+		if !present[tag] {
+			existing.Content = append(existing.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag})
+			changed = true
+		}
+	}
+	if !changed {
+		return content, false, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", false, err
+	}
+	enc.Close()
+	return "---\n" + buf.String() + "---\n" + body, true, nil
+}
+
+func tagsPatchFilename(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "_tags.patch"
+}
+
+// buildTagsPatch returns a unified diff from oldContent to newContent for
+// display path name. Only the frontmatter differs, so one hunk with up to
+// three lines of context on each side covers the change.
+func buildTagsPatch(name, oldContent, newContent string) string {
+	oldLines := strings.SplitAfter(oldContent, "\n")
+	newLines := strings.SplitAfter(newContent, "\n")
+	common := 0
+	for common < len(oldLines) && common < len(newLines) &&
+		oldLines[len(oldLines)-1-common] == newLines[len(newLines)-1-common] {
+		common++
+	}
+	prefix := 0
+	for prefix < len(oldLines)-common && prefix < len(newLines)-common && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	before := oldLines[max(0, prefix-3):prefix]
+	removed := oldLines[prefix : len(oldLines)-common]
+	added := newLines[prefix : len(newLines)-common]
+	after := oldLines[len(oldLines)-common:]
+	if len(after) > 3 {
+		after = after[:3]
+	}
+	for len(after) > 0 && after[len(after)-1] == "" {
+		after = after[:len(after)-1]
+	}
+
+	start := prefix - len(before) + 1
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+	fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(start, len(before)+len(removed)+len(after)), hunkRange(start, len(before)+len(added)+len(after)))
+	for _, line := range before {
+		b.WriteString(" " + withNewline(line))
+	}
+	for _, line := range removed {
+		b.WriteString("-" + withNewline(line))
+	}
+	for _, line := range added {
+		b.WriteString("+" + withNewline(line))
+	}
+	for _, line := range after {
+		b.WriteString(" " + withNewline(line))
+	}
+	return b.String()
+}
+
+// hunkRange formats the line range of a hunk; an empty range names the line
+// before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func withNewline(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return line + "\n\\ No newline at end of file\n"
+}
+
+// offerSourceTags adds tags to the frontmatter of the source at path with
+// -apply-tags; otherwise it writes the change as <name>_tags.patch next to
+// the source. It returns the source content as it is afterwards.
+func offerSourceTags(path string, data []byte, tags []string, cfg Config) ([]byte, error) {
+	patchPath := tagsPatchFilename(path)
+	updated, changed, err := addSourceTags(string(data), tags)
+	if err != nil || !changed {
+		os.Remove(patchPath)
+		return data, err
+	}
+	if cfg.ApplyTags {
+		if err := writeFileAtomic(path, []byte(updated), 0o644); err != nil {
+			return data, err
+		}
+		os.Remove(patchPath)
+		return []byte(updated), nil
+	}
+	name := filepath.ToSlash(displayPath(path, cfg.RootDir))
+	return data, writeFileAtomic(patchPath, []byte(buildTagsPatch(name, string(data), updated)), 0o644)
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestBuildTagsPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "tags added to existing frontmatter",
+			old:  "---\ntitle: X\n---\nText\n",
+			new:  "---\ntitle: X\ntags: [a]\n---\nText\n",
+			want: "--- a/n.md\n+++ b/n.md\n@@ -1,4 +1,5 @@\n ---\n title: X\n+tags: [a]\n ---\n Text\n",
+		},
+		{
+			name: "frontmatter added to a document without one",
+			old:  "Text\nmehr\n",
+			new:  "---\ntags: [a]\n---\nText\nmehr\n",
+			want: "--- a/n.md\n+++ b/n.md\n@@ -1,2 +1,5 @@\n+---\n+tags: [a]\n+---\n Text\n mehr\n",
+		},
+		{
+			name: "tags line replaced in a file without final newline",
+			old:  "---\ntags: [a]\n---\nText",
+			new:  "---\ntags: [a, b]\n---\nText",
+			want: "--- a/n.md\n+++ b/n.md\n@@ -1,4 +1,4 @@\n ---\n-tags: [a]\n+tags: [a, b]\n ---\n Text\n\\ No newline at end of file\n",
+		},
+		{
+			name: "context is limited to three lines",
+			old:  "---\na: 1\nb: 2\nc: 3\nd: 4\n---\nText\nZeile 2\nZeile 3\nZeile 4\n",
+			new:  "---\na: 1\nb: 2\nc: 3\nd: 4\ntags: [x]\n---\nText\nZeile 2\nZeile 3\nZeile 4\n",
+			want: "--- a/n.md\n+++ b/n.md\n@@ -3,6 +3,7 @@\n b: 2\n c: 3\n d: 4\n+tags: [x]\n ---\n Text\n Zeile 2\n",
+		},
+		{
+			name: "empty document",
+			old:  "",
+			new:  "---\ntags: [a]\n---\n",
+			want: "--- a/n.md\n+++ b/n.md\n@@ -0,0 +1,3 @@\n+---\n+tags: [a]\n+---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildTagsPatch("n.md", tt.old, tt.new); got != tt.want {
+				t.Errorf("buildTagsPatch() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+const updateRepo = "danst0/Chief-Summarizer"
+
+// Release assets used to verify a download. checksums.txt is in sha256sum
+// format; checksums.txt.sig is an Ed25519 signature over it.
+const (
+	checksumsAsset = "checksums.txt"
+	signatureAsset = "checksums.txt.sig"
+)
+
+// Supported values for updates.channel.
+const (
+	channelStable     = "stable"
+	channelPrerelease = "prerelease"
+)
+
+var updateChannels = []string{channelStable, channelPrerelease}
+
+var updateClient = &http.Client{Timeout: 5 * time.Minute}
+
+// releaseInfo is a GitHub release that has a binary for this platform.
+type releaseInfo struct {
+	Version semver.Version
+	Tag     string
+	URL     string
+	Assets  map[string]string
+}
+
+// updateState records when updates were last checked, so automatic checks
+// run at most once per updates.check_interval.
+type updateState struct {
+	LastCheck time.Time `json:"last_check"`
+	Latest    string    `json:"latest,omitempty"`
+}
+
+// updateStatePath returns update.json in the directory of the default state file.
+func updateStatePath(homeDir string) string {
+	return filepath.Join(filepath.Dir(defaultStatePath(homeDir)), "update.json")
+}
+
+func readUpdateState(path string) updateState {
+	var st updateState
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &st)
+	}
+	return st
+}
+
+func writeUpdateState(path string, st updateState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// releaseAssetName is the binary asset for the running platform, as produced
+// by scripts/build-release.sh.
+func releaseAssetName() string {
+	name := fmt.Sprintf("chief-summarizer-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// latestRelease returns the newest published release on channel that has a
+// binary for this platform. Pre-releases are only considered on the
+// prerelease channel.
+func latestRelease(channel string) (*releaseInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/"+updateRepo+"/releases?per_page=30", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := updateClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("github releases request failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var releases []struct {
+		TagName    string `json:"tag_name"`
+		HTMLURL    string `json:"html_url"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+		Assets     []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, err
+	}
+
+	var best *releaseInfo
+	for _, rel := range releases {
+		if rel.Draft || (rel.Prerelease && channel != channelPrerelease) {
+			continue
+		}
+		v, err := semver.Parse(strings.TrimPrefix(rel.TagName, "v"))
+		if err != nil || (len(v.Pre) > 0 && channel != channelPrerelease) {
+			continue
+		}
+		info := &releaseInfo{Version: v, Tag: rel.TagName, URL: rel.HTMLURL, Assets: make(map[string]string)}
+		for _, asset := range rel.Assets {
+			info.Assets[asset.Name] = asset.URL
+		}
+		if _, ok := info.Assets[releaseAssetName()]; !ok {
+			continue
+		}
+		if best == nil || v.GT(best.Version) {
+			best = info
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no %s release with a %s binary found", channel, releaseAssetName())
+	}
+	return best, nil
+}
+
+func downloadAsset(url string) ([]byte, error) {
+	resp, err := updateClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("download %s failed: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 512<<20))
+}
+
+// verifyDownload checks binary against the SHA-256 listed for name in the
+// release's checksums.txt (or <name>.sha256). With a public key configured,
+// checksums.txt must also carry a valid Ed25519 signature.
+func verifyDownload(rel *releaseInfo, name string, binary []byte, publicKey string) error {
+	var expected string
+	if url, ok := rel.Assets[checksumsAsset]; ok {
+		sums, err := downloadAsset(url)
+		if err != nil {
+			return err
+		}
+		if publicKey != "" {
+			if err := verifySignature(rel, sums, publicKey); err != nil {
+				return err
+			}
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(sums))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+				expected = fields[0]
+				break
+			}
+		}
+		if expected == "" {
+			return fmt.Errorf("%s does not list %s", checksumsAsset, name)
+		}
+	} else if url, ok := rel.Assets[name+".sha256"]; ok && publicKey == "" {
+		sum, err := downloadAsset(url)
+		if err != nil {
+			return err
+		}
+		if fields := strings.Fields(string(sum)); len(fields) > 0 {
+			expected = fields[0]
+		}
+	} else if publicKey != "" {
+		return fmt.Errorf("release %s has no signed %s", rel.Tag, checksumsAsset)
+	} else {
+		return fmt.Errorf("release %s has no checksums; refusing to install an unverified binary", rel.Tag)
+	}
+
+	actual := sha256.Sum256(binary)
+	if !strings.EqualFold(expected, hex.EncodeToString(actual[:])) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %x", name, expected, actual)
+	}
+	return nil
+}
+
+func verifySignature(rel *releaseInfo, sums []byte, publicKey string) error {
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("updates.public_key: %w", err)
+	}
+	url, ok := rel.Assets[signatureAsset]
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Tag, signatureAsset)
+	}
+	sig, err := downloadAsset(url)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, sums, sig) {
+		return fmt.Errorf("invalid signature on %s of release %s", checksumsAsset, rel.Tag)
+	}
+	return nil
+}
+
+// parsePublicKey reads an Ed25519 public key in PEM form, as written by
+// `openssl pkey -pubout`.
+func parsePublicKey(text string) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, errors.New("expected a PEM encoded public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an Ed25519 key")
+	}
+	return key, nil
+}
+
+// executablePath resolves the running binary through symlinks, so updates
+// replace the real file rather than a link to it.
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// previousBinaryPath is where installBinary keeps the replaced binary.
+func previousBinaryPath(exe string) string {
+	return exe + ".old"
+}
+
+// installBinary replaces exe with binary and keeps the current binary at
+// previousBinaryPath for rollback.
+func installBinary(exe string, binary []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+tempFileMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+	if err := os.Rename(exe, previousBinaryPath(exe)); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, exe); err != nil {
+		os.Rename(previousBinaryPath(exe), exe)
+		return err
+	}
+	return nil
+}
+
+// rollbackBinary swaps exe with the binary kept by the last update, so a
+// second rollback returns to the newer version.
+func rollbackBinary(exe string) error {
+	previous := previousBinaryPath(exe)
+	if _, err := os.Stat(previous); err != nil {
+		return fmt.Errorf("no previous binary at %s", previous)
+	}
+	swap := exe + ".rollback"
+	if err := os.Rename(exe, swap); err != nil {
+		return err
+	}
+	if err := os.Rename(previous, exe); err != nil {
+		os.Rename(swap, exe)
+		return err
+	}
+	return os.Rename(swap, previous)
+}
+
+// updateSettings holds the updates section of the config file.
+type updateSettings struct {
+	Channel       string
+	CheckInterval time.Duration
+	PublicKey     string
+}
+
+func loadUpdateSettings(configFile *ConfigFile) (updateSettings, error) {
+	s := updateSettings{Channel: channelStable, CheckInterval: 24 * time.Hour, PublicKey: configFile.Updates.PublicKey}
+	if configFile.Updates.Channel != "" {
+		s.Channel = configFile.Updates.Channel
+	}
+	if !containsString(updateChannels, s.Channel) {
+		return s, fmt.Errorf("invalid updates.channel %q (expected one of: %s)", s.Channel, strings.Join(updateChannels, ", "))
+	}
+	if configFile.Updates.CheckInterval != "" {
+		interval, err := time.ParseDuration(configFile.Updates.CheckInterval)
+		if err != nil {
+			return s, fmt.Errorf("invalid updates.check_interval: %w", err)
+		}
+		s.CheckInterval = interval
+	}
+	return s, nil
+}
+
+// applyUpdate downloads, verifies and installs rel.
+func applyUpdate(rel *releaseInfo, settings updateSettings) (string, error) {
+	exe, err := executablePath()
+	if err != nil {
+		return "", fmt.Errorf("locate executable: %w", err)
+	}
+	name := releaseAssetName()
+	binary, err := downloadAsset(rel.Assets[name])
+	if err != nil {
+		return "", err
+	}
+	if settings.PublicKey == "" {
+		errorf("WARN updates.public_key is not set: v%s is only checked against checksums from the same release, which does not detect a tampered release\n", rel.Version)
+	}
+	if err := verifyDownload(rel, name, binary, settings.PublicKey); err != nil {
+		return "", err
+	}
+	if err := installBinary(exe, binary); err != nil {
+		return "", fmt.Errorf("install %s: %w", exe, err)
+	}
+	return exe, nil
+}
+
+// autoUpdate checks for and installs a newer release at most once per
+// check interval. Problems are reported as warnings and never stop the run.
+func autoUpdate(cfg Config) {
+	settings := cfg.Updates
+	statePath := cfg.UpdateStatePath
+	st := readUpdateState(statePath)
+	now := time.Now()
+	if settings.CheckInterval > 0 && now.Sub(st.LastCheck) < settings.CheckInterval {
+		return
+	}
+
+	rel, err := latestRelease(settings.Channel)
+	st.LastCheck = now
+	if err == nil {
+		st.Latest = rel.Version.String()
+	}
+	if saveErr := writeUpdateState(statePath, st); saveErr != nil && cfg.Verbose {
+		errorf("WARN failed to save update state: %v\n", saveErr)
+	}
+	if err != nil {
+		if cfg.Verbose {
+			errorf("WARN update check failed: %v\n", err)
+		}
+		return
+	}
+
+	current := semver.MustParse(version)
+	if rel.Version.LTE(current) {
+		if cfg.Verbose {
+			statusf(cfg, "INFO v%s is the latest %s release\n", version, settings.Channel)
+		}
+		return
+	}
+	statusf(cfg, "INFO updating v%s -> v%s\n", version, rel.Version)
+	exe, err := applyUpdate(rel, settings)
+	if err != nil {
+		errorf("WARN update to v%s failed: %v\n", rel.Version, err)
+		return
+	}
+	statusf(cfg, "INFO updated %s to v%s (previous binary kept as %s; takes effect next run)\n", exe, rel.Version, previousBinaryPath(exe))
+}
+
+// runSelfUpdate implements `chief-summarizer self-update [flags]`.
+func runSelfUpdate(args []string) int {
+	flags := flag.NewFlagSet("self-update", flag.ExitOnError)
+	check := flags.Bool("check", false, "Only report whether a newer release is available")
+	rollback := flags.Bool("rollback", false, "Restore the binary replaced by the last update")
+	channel := flags.String("channel", "", "Release channel: "+strings.Join(updateChannels, ", ")+" (default updates.channel or stable)")
+	configPath := flags.String("config", "", "Path of the config file (default $XDG_CONFIG_HOME/chiefsummarizer.yaml)")
+	profile := flags.String("profile", "", "Config profile to layer over the base settings (default $CHIEF_SUMMARIZER_PROFILE)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chief-summarizer self-update [-check | -rollback] [-channel stable|prerelease]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *rollback {
+		exe, err := executablePath()
+		if err != nil {
+			errorf("ERR  locate executable: %v\n", err)
+			return 1
+		}
+		if err := rollbackBinary(exe); err != nil {
+			errorf("ERR  rollback failed: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Restored the previous binary at %s (the replaced one is now %s).\n", exe, previousBinaryPath(exe))
+		return 0
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		errorf("ERR  determine home directory: %v\n", err)
+		return 1
+	}
+	loaded, err := loadConfig(configPathFor(*configPath, homeDir), profileFor(*profile))
+	if err != nil {
+		errorf("ERR  failed to load config file: %v\n", err)
+		return 1
+	}
+	configFile := loaded.File
+	if *channel != "" {
+		configFile.Updates.Channel = *channel
+	}
+	settings, err := loadUpdateSettings(configFile)
+	if err != nil {
+		errorf("ERR  %v\n", err)
+		return 2
+	}
+
+	rel, err := latestRelease(settings.Channel)
+	statePath := updateStatePath(homeDir)
+	st := readUpdateState(statePath)
+	st.LastCheck = time.Now()
+	if err == nil {
+		st.Latest = rel.Version.String()
+	}
+	writeUpdateState(statePath, st)
+	if err != nil {
+		errorf("ERR  update check failed: %v\n", err)
+		return 1
+	}
+
+	current := semver.MustParse(version)
+	if rel.Version.LTE(current) {
+		fmt.Printf("chief-summarizer v%s is the latest %s release.\n", version, settings.Channel)
+		return 0
+	}
+	if *check {
+		fmt.Printf("v%s is available (current: v%s): %s\n", rel.Version, version, rel.URL)
+		return 0
+	}
+	exe, err := applyUpdate(rel, settings)
+	if err != nil {
+		errorf("ERR  update to v%s failed: %v\n", rel.Version, err)
+		return 1
+	}
+	fmt.Printf("Updated %s to v%s. The previous binary is kept as %s; undo with `chief-summarizer self-update -rollback`.\n", exe, rel.Version, previousBinaryPath(exe))
+	return 0
+}
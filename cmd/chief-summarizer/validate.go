@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// requiredHeadings must appear, in this order, in every final summary.
+var requiredHeadings = []string{"## Ultra-Kurzfassung", "## Ausführliche Zusammenfassung"}
+
+// promptLeakMarkers are sentences only our own prompts contain; a summary
+// that repeats one has echoed its prompt. Generic phrases a diary might use
+// ("output format", "Chunk 1:") are deliberately left out.
+var promptLeakMarkers = []string{
+	"you are \"chief summarizer\", an assistant",
+	"now produce only the markdown",
+	"do not add any footer or metadata lines; the system will append them",
+	"original document length category:",
+}
+
+var languageStopwords = map[string][]string{
+	"de": {"der", "die", "das", "und", "ich", "nicht", "ist", "mit", "ein", "eine", "zu", "den", "von", "auf", "für", "sich", "auch", "wir", "war", "habe", "mich", "mir", "aber", "wie"},
+	"en": {"the", "and", "i", "is", "to", "of", "in", "that", "it", "was", "with", "for", "not", "we", "have", "this", "my", "me", "but", "on", "be", "are", "at"},
+}
+
+var wordPattern = regexp.MustCompile(`\p{L}+`)
+
+// detectLanguage guesses "de" or "en" from stopword frequency. It returns ""
+// when the text is too short or ambiguous to tell.
+func detectLanguage(text string) string {
+	counts := make(map[string]int, len(languageStopwords))
+	lookup := make(map[string][]string)
+	for lang, words := range languageStopwords {
+		for _, w := range words {
+			lookup[w] = append(lookup[w], lang)
+		}
+	}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		for _, lang := range lookup[word] {
+			counts[lang]++
+		}
+	}
+	best, second := "", 0
+	bestCount := 0
+	for lang, count := range counts {
+		if count > bestCount {
+			second = bestCount
+			best, bestCount = lang, count
+		} else if count > second {
+			second = count
+		}
+	}
+	if bestCount < 5 || float64(bestCount) < 1.5*float64(second) {
+		return ""
+	}
+	return best
+}
+
+var languageNames = map[string]string{"de": "German", "en": "English"}
+
+// validateSummary checks a final summary against the format requested by
+// buildFinalPrompt and returns a list of problems (empty if it passes).
+func validateSummary(summary, sourceLang string, cfg Config) []string {
+	var problems []string
+	positions := make([]int, len(requiredHeadings))
+	for i, heading := range requiredHeadings {
+		positions[i] = strings.Index(summary, heading)
+		if positions[i] < 0 {
+			problems = append(problems, fmt.Sprintf("missing heading %q", heading))
+		}
+	}
+	if positions[0] >= 0 && positions[1] >= 0 {
+		if positions[0] > positions[1] {
+			problems = append(problems, "headings are in the wrong order")
+		} else {
+			detailed := strings.TrimSpace(summary[positions[1]+len(requiredHeadings[1]):])
+			if detailed == "" {
+				problems = append(problems, fmt.Sprintf("section %q is empty", requiredHeadings[1]))
+			}
+			short := strings.TrimSpace(summary[positions[0]+len(requiredHeadings[0]) : positions[1]])
+			if short == "" {
+				problems = append(problems, fmt.Sprintf("section %q is empty", requiredHeadings[0]))
+			}
+		}
+	}
+
+	length := len([]rune(summary))
+	if cfg.MinSummaryLength > 0 && length < cfg.MinSummaryLength {
+		problems = append(problems, fmt.Sprintf("too short (%d characters, minimum %d)", length, cfg.MinSummaryLength))
+	}
+	if cfg.MaxSummaryLength > 0 && length > cfg.MaxSummaryLength {
+		problems = append(problems, fmt.Sprintf("too long (%d characters, maximum %d)", length, cfg.MaxSummaryLength))
+	}
+
+	if sourceLang != "" {
+		if lang := detectLanguage(summary); lang != "" && lang != sourceLang {
+			problems = append(problems, fmt.Sprintf("written in %s but the source is %s", languageNames[lang], languageNames[sourceLang]))
+		}
+	}
+
+	lower := strings.ToLower(summary)
+	for _, marker := range promptLeakMarkers {
+		if strings.Contains(lower, marker) {
+			problems = append(problems, fmt.Sprintf("contains prompt text (%q)", marker))
+			break
+		}
+	}
+	return problems
+}
+
+// buildCorrectivePrompt repeats the final prompt together with the reasons the
+// previous answer was rejected.
+func buildCorrectivePrompt(finalPrompt string, problems []string, sourceLang string) string {
+	var b strings.Builder
+	b.WriteString(finalPrompt)
+	b.WriteString("\nIMPORTANT: Your previous answer was rejected for the following reasons:\n")
+	for _, problem := range problems {
+		b.WriteString("- ")
+		b.WriteString(problem)
+		b.WriteString("\n")
+	}
+	if name, ok := languageNames[sourceLang]; ok {
+		b.WriteString(fmt.Sprintf("- Remember: the answer must be written in %s.\n", name))
+	}
+	b.WriteString("Produce a corrected summary that fixes all of these problems and follows the output format exactly.\nDo not repeat these instructions in your answer.\n")
+	return b.String()
+}
+
+// finalizeSummary runs the final merge prompt and validates the result,
+// retrying with a corrective prompt up to cfg.ValidationRetries times.
+func finalizeSummary(path, finalPrompt, sourceLang string, cfg Config) (string, error) {
+	prompt := finalPrompt
+	var problems []string
+	for attempt := 0; attempt <= cfg.ValidationRetries; attempt++ {
+		resp, err := callOllama(prompt)
+		if err != nil {
+			return "", err
+		}
+		summary := stripThinkBlocks(resp)
+		if cfg.DisableValidation {
+			return summary, nil
+		}
+		problems = validateSummary(summary, sourceLang, cfg)
+		if len(problems) == 0 {
+			return summary, nil
+		}
+		if attempt < cfg.ValidationRetries {
+			statusf(cfg, "RETRY %s (summary rejected: %s; attempt %d/%d)\n", displayPath(path, cfg.RootDir), strings.Join(problems, "; "), attempt+2, cfg.ValidationRetries+1)
+			prompt = buildCorrectivePrompt(finalPrompt, problems, sourceLang)
+		}
+	}
+	return "", fmt.Errorf("summary failed validation after %d attempts: %s", cfg.ValidationRetries+1, strings.Join(problems, "; "))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSummary(t *testing.T) {
+	const (
+		short    = "Ich war mit Anna in Berlin und wir haben über die Arbeit geredet."
+		detailed = "Am Morgen bin ich mit dem Zug nach Berlin gefahren. Dort habe ich Anna getroffen, und wir sind den ganzen Tag durch die Stadt gelaufen. Wir haben auch über die neue Stelle geredet, die ich mir nicht zutraue."
+	)
+	german := "## Ultra-Kurzfassung\n" + short + "\n\n## Ausführliche Zusammenfassung\n" + detailed
+	english := "## Ultra-Kurzfassung\nI met Anna in Berlin and we talked about the new job.\n\n## Ausführliche Zusammenfassung\nIn the morning I took the train to Berlin. There I met Anna and we walked through the city all day. We also talked about the new job that I do not think I can do."
+	cfg := Config{MinSummaryLength: 80, MaxSummaryLength: 20000}
+
+	tests := []struct {
+		name       string
+		summary    string
+		sourceLang string
+		cfg        Config
+		want       []string
+	}{
+		{name: "valid summary", summary: german, sourceLang: "de", cfg: cfg},
+		{name: "unknown source language skips the language check", summary: english, cfg: cfg},
+		{
+			name:    "missing heading",
+			summary: "## Ultra-Kurzfassung\n" + short + "\n\n" + detailed,
+			cfg:     cfg,
+			want:    []string{`missing heading "## Ausführliche Zusammenfassung"`},
+		},
+		{
+			name:    "headings in the wrong order",
+			summary: "## Ausführliche Zusammenfassung\n" + detailed + "\n\n## Ultra-Kurzfassung\n" + short,
+			cfg:     cfg,
+			want:    []string{"headings are in the wrong order"},
+		},
+		{
+			name:    "empty sections",
+			summary: "## Ultra-Kurzfassung\n\n## Ausführliche Zusammenfassung\n\n",
+			cfg:     Config{},
+			want:    []string{`section "## Ausführliche Zusammenfassung" is empty`, `section "## Ultra-Kurzfassung" is empty`},
+		},
+		{
+			name:    "too short",
+			summary: "## Ultra-Kurzfassung\nKurz.\n\n## Ausführliche Zusammenfassung\nAuch kurz.",
+			cfg:     cfg,
+			want:    []string{"too short (70 characters, minimum 80)"},
+		},
+		{
+			name:    "too long",
+			summary: german,
+			cfg:     Config{MaxSummaryLength: 100},
+			want:    []string{fmt.Sprintf("too long (%d characters, maximum 100)", len([]rune(german)))},
+		},
+		{name: "length limits of 0 are off", summary: "## Ultra-Kurzfassung\nKurz.\n\n## Ausführliche Zusammenfassung\nAuch kurz."},
+		{
+			name:       "wrong language",
+			summary:    english,
+			sourceLang: "de",
+			cfg:        cfg,
+			want:       []string{"written in English but the source is German"},
+		},
+		{
+			name:    "echoed prompt",
+			summary: german + "\n\nNow produce ONLY the markdown summary as specified above.",
+			cfg:     cfg,
+			want:    []string{`contains prompt text ("now produce only the markdown")`},
+		},
+		{
+			name:    "echoed prompt header",
+			summary: "You are \"Chief Summarizer\", an assistant that creates concise summaries.\n" + german,
+			cfg:     cfg,
+			want:    []string{`contains prompt text ("you are \"chief summarizer\", an assistant")`},
+		},
+		{
+			name:    "words that also occur in prompts are fine",
+			summary: german + "\n\nDas Output Format der Präsentation stand schon, Chunk 1: erledigt, Summary 1: offen.",
+			cfg:     cfg,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateSummary(tt.summary, tt.sourceLang, tt.cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,17 +3,6 @@ module chief-summarizer
 go 1.25.4
 
 require (
-	github.com/blang/semver v3.5.1+incompatible // indirect
-	github.com/golang/protobuf v1.3.2 // indirect
-	github.com/google/go-github/v30 v30.1.0 // indirect
-	github.com/google/go-querystring v1.0.0 // indirect
-	github.com/inconshreveable/go-update v0.0.0-20160112193335-8152e7eb6ccf // indirect
-	github.com/rhysd/go-github-selfupdate v1.2.3 // indirect
-	github.com/tcnksm/go-gitconfig v0.1.2 // indirect
-	github.com/ulikunitz/xz v0.5.9 // indirect
-	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad // indirect
-	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3 // indirect
-	golang.org/x/oauth2 v0.0.0-20181106182150-f42d05182288 // indirect
-	google.golang.org/appengine v1.3.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/blang/semver v3.5.1+incompatible
+	gopkg.in/yaml.v3 v3.0.1
 )
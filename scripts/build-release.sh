@@ -34,6 +34,22 @@ GOOS=darwin GOARCH=arm64 go build -ldflags="${LDFLAGS}" -o dist/chief-summarizer
 echo "→ Building Windows AMD64..."
 GOOS=windows GOARCH=amd64 go build -ldflags="${LDFLAGS}" -o dist/chief-summarizer-windows-amd64.exe ./cmd/chief-summarizer
 
+echo "→ Writing checksums..."
+(
+    cd dist
+    if command -v sha256sum >/dev/null 2>&1; then
+        sha256sum chief-summarizer-* > checksums.txt
+    else
+        shasum -a 256 chief-summarizer-* > checksums.txt
+    fi
+)
+
+# Sign the checksums if an Ed25519 private key is given (see RELEASE.md)
+if [ -n "${SIGNING_KEY}" ]; then
+    echo "→ Signing checksums..."
+    openssl pkeyutl -sign -inkey "${SIGNING_KEY}" -rawin -in dist/checksums.txt -out dist/checksums.txt.sig
+fi
+
 echo
 echo "✓ All binaries built successfully in dist/"
 echo